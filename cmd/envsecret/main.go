@@ -0,0 +1,198 @@
+// Command envsecret manages age-encrypted secret bundles read by store/age.
+//
+// Usage:
+//
+//	envsecret bundle list [flags]
+//	envsecret bundle add [flags] ID VALUE
+//	envsecret bundle rm [flags] ID
+//
+// VALUE is stored as a JSON object if it parses as one, and otherwise as a single value.
+// A VALUE of "-" is read from standard input.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+
+	agestore "github.com/gavincabbage/envsecret/store/age"
+)
+
+const (
+	bundleEnv     = "ENVSECRET_AGE_BUNDLE"
+	recipientsEnv = "ENVSECRET_AGE_RECIPIENTS_FILE"
+	defaultBundle = "secrets.age"
+)
+
+const usage = `usage:
+  envsecret bundle list [flags]
+  envsecret bundle add [flags] ID VALUE
+  envsecret bundle rm [flags] ID
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "envsecret:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command described by args.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) < 2 || args[0] != "bundle" {
+		fmt.Fprint(stderr, usage)
+		return errors.New("unknown command")
+	}
+
+	cmd := args[1]
+	flags := flag.NewFlagSet("envsecret bundle "+cmd, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		bundlePath     = flags.String("f", env(bundleEnv, defaultBundle), "bundle `file`")
+		identityPath   = flags.String("i", os.Getenv(agestore.IdentityFileEnv), "age identity `file` used to decrypt the bundle")
+		recipientsPath = flags.String("R", os.Getenv(recipientsEnv), "age recipients `file` the bundle is encrypted to")
+	)
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list":
+		if flags.NArg() != 0 {
+			return errors.New("list takes no arguments")
+		}
+
+		bundle, err := load(*bundlePath, *identityPath)
+		if err != nil {
+			return err
+		}
+		for _, id := range bundle.IDs() {
+			fmt.Fprintln(stdout, id)
+		}
+
+		return nil
+
+	case "add":
+		if flags.NArg() != 2 {
+			return errors.New("add requires ID and VALUE arguments")
+		}
+
+		raw := flags.Arg(1)
+		if raw == "-" {
+			b, err := io.ReadAll(stdin)
+			if err != nil {
+				return err
+			}
+			raw = strings.TrimSuffix(string(b), "\n")
+		}
+
+		bundle, err := load(*bundlePath, *identityPath)
+		if err != nil {
+			return err
+		}
+		bundle[flags.Arg(0)] = parse(raw)
+
+		return save(bundle, *bundlePath, *recipientsPath)
+
+	case "rm":
+		if flags.NArg() != 1 {
+			return errors.New("rm requires an ID argument")
+		}
+
+		bundle, err := load(*bundlePath, *identityPath)
+		if err != nil {
+			return err
+		}
+		if _, found := bundle[flags.Arg(0)]; !found {
+			return fmt.Errorf("%s is not in the bundle", flags.Arg(0))
+		}
+		delete(bundle, flags.Arg(0))
+
+		return save(bundle, *bundlePath, *recipientsPath)
+	}
+
+	fmt.Fprint(stderr, usage)
+	return fmt.Errorf("unknown bundle command %q", cmd)
+}
+
+// load decrypts the bundle at path, or returns an empty bundle if it does not yet exist.
+func load(path, identityPath string) (agestore.Bundle, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(agestore.Bundle), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if identityPath == "" {
+		return nil, fmt.Errorf("an identity file is required, set -i or %s", agestore.IdentityFileEnv)
+	}
+
+	identities, err := agestore.ReadIdentities(identityPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading identities: %w", err)
+	}
+
+	return agestore.Decrypt(bytes.NewReader(b), identities...)
+}
+
+// save encrypts the bundle to the recipients and atomically replaces the file at path.
+func save(bundle agestore.Bundle, path, recipientsPath string) error {
+	if recipientsPath == "" {
+		return fmt.Errorf("a recipients file is required, set -R or %s", recipientsEnv)
+	}
+
+	recipients, err := agestore.ReadRecipients(recipientsPath)
+	if err != nil {
+		return fmt.Errorf("reading recipients: %w", err)
+	}
+
+	return write(bundle, path, recipients)
+}
+
+// write the encrypted bundle to a temporary file beside path and rename it into place.
+func write(bundle agestore.Bundle, path string, recipients []age.Recipient) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := bundle.Encrypt(f, recipients...); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// parse the value as a JSON object, falling back to a single value map.
+func parse(raw string) map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil || m == nil {
+		return map[string]interface{}{
+			"value": raw,
+		}
+	}
+
+	return m
+}
+
+// env returns the value of the environment variable, or def if it is unset.
+func env(name, def string) string {
+	if v, found := os.LookupEnv(name); found {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+
+	agestore "github.com/gavincabbage/envsecret/store/age"
+)
+
+func TestRun_Bundle(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var (
+		identityFile   = filepath.Join(dir, "identity.txt")
+		recipientsFile = filepath.Join(dir, "recipients.txt")
+		bundleFile     = filepath.Join(dir, "secrets.age")
+	)
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(recipientsFile, []byte(identity.Recipient().String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := []string{"-f", bundleFile, "-i", identityFile, "-R", recipientsFile}
+	bundle := func(stdin string, args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"bundle", args[0]}, append(flags, args[1:]...)...), strings.NewReader(stdin), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	_, err = bundle("", "add", "db-creds", `{"username":"user","password":"pass"}`)
+	assert.NoError(t, err)
+	_, err = bundle("abc123\n", "add", "api-key", "-")
+	assert.NoError(t, err)
+	_, err = bundle("", "add", "old-key", "old")
+	assert.NoError(t, err)
+	_, err = bundle("", "rm", "old-key")
+	assert.NoError(t, err)
+
+	out, err := bundle("", "list")
+	assert.NoError(t, err)
+	assert.Equal(t, "api-key\ndb-creds\n", out)
+
+	_, err = bundle("", "rm", "missing")
+	assert.Error(t, err)
+
+	t.Setenv(agestore.IdentityFileEnv, identityFile)
+	store, err := agestore.Open(bundleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := store.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"username": "user", "password": "pass"}, actual)
+
+	actual, err = store.Get("api-key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "abc123"}, actual)
+}
+
+func TestRun_Errors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	bundleFile := filepath.Join(t.TempDir(), "secrets.age")
+
+	assert.Error(t, run(nil, nil, &stdout, &stderr))
+	assert.Error(t, run([]string{"bundle", "unknown"}, nil, &stdout, &stderr))
+	assert.Error(t, run([]string{"bundle", "add", "-f", bundleFile, "-R", "", "id", "value"}, nil, &stdout, &stderr))
+	assert.Error(t, run([]string{"bundle", "add", "-f", bundleFile, "id"}, nil, &stdout, &stderr))
+}
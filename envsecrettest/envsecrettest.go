@@ -0,0 +1,163 @@
+// Package envsecrettest provides utilities for testing code that uses envsecret, without any
+// secret store backend.
+package envsecrettest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/memory"
+)
+
+// MemoryStore is an in-memory envsecret.Store which records the identifiers it is asked for and
+// can be made to fail or slow down for individual identifiers. It is safe for concurrent use.
+type MemoryStore struct {
+	store *memory.MemoryStore
+
+	mu      sync.Mutex
+	errs    map[string]error
+	latency map[string]time.Duration
+	calls   []string
+}
+
+// NewMemoryStore returns a MemoryStore holding a copy of the given secrets, keyed by identifier.
+func NewMemoryStore(secrets map[string]map[string]interface{}) *MemoryStore {
+	return &MemoryStore{
+		store:   memory.New(secrets),
+		errs:    make(map[string]error),
+		latency: make(map[string]time.Duration),
+	}
+}
+
+// NewMemoryStoreFromFile returns a MemoryStore holding the fixtures in the JSON or YAML file at
+// the given path, as read by envsecret.ReadFixtures, failing the test should it be invalid.
+func NewMemoryStoreFromFile(t testing.TB, path string) *MemoryStore {
+	t.Helper()
+
+	fixtures, err := envsecret.ReadFixtures(path)
+	if err != nil {
+		t.Fatalf("reading fixtures: %v", err)
+	}
+
+	return NewMemoryStore(fixtures)
+}
+
+// Get implements envsecret.Store, recording the call and applying any latency and error set for
+// the identifier.
+func (s *MemoryStore) Get(id string) (map[string]interface{}, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, id)
+	err, failed := s.errs[id]
+	latency, found := s.latency[id]
+	if !found {
+		latency = s.latency[""]
+	}
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+
+	if failed {
+		return nil, err
+	}
+
+	return s.store.Get(id)
+}
+
+// Put implements envsecret.WritableStore.
+func (s *MemoryStore) Put(id string, secret map[string]interface{}) error {
+	return s.store.Put(id, secret)
+}
+
+// Delete implements envsecret.WritableStore.
+func (s *MemoryStore) Delete(id string) error {
+	return s.store.Delete(id)
+}
+
+// List implements envsecret.ListStore.
+func (s *MemoryStore) List(prefix string) ([]string, error) {
+	return s.store.List(prefix)
+}
+
+// SetError makes Get return err for the given identifier, or behave normally again if err is nil.
+func (s *MemoryStore) SetError(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.errs, id)
+		return
+	}
+	s.errs[id] = err
+}
+
+// SetLatency delays Get for the given identifier by d. An empty identifier sets the latency of
+// every identifier without its own.
+func (s *MemoryStore) SetLatency(id string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency[id] = d
+}
+
+// Calls returns the identifiers passed to Get, in order.
+func (s *MemoryStore) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.calls...)
+}
+
+// Reset forgets the calls recorded so far.
+func (s *MemoryStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+}
+
+// AssertFetched reports a test error for each of the identifiers Get has not been called with.
+func (s *MemoryStore) AssertFetched(t testing.TB, ids ...string) bool {
+	t.Helper()
+
+	fetched := s.fetched()
+	ok := true
+	for _, id := range ids {
+		if !fetched[id] {
+			t.Errorf("expected secret %q to have been fetched, fetched %q", id, s.Calls())
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// AssertNotFetched reports a test error for each of the identifiers Get has been called with.
+func (s *MemoryStore) AssertNotFetched(t testing.TB, ids ...string) bool {
+	t.Helper()
+
+	fetched := s.fetched()
+	ok := true
+	for _, id := range ids {
+		if fetched[id] {
+			t.Errorf("expected secret %q not to have been fetched", id)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// fetched returns the set of identifiers passed to Get.
+func (s *MemoryStore) fetched() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fetched := make(map[string]bool, len(s.calls))
+	for _, id := range s.calls {
+		fetched[id] = true
+	}
+
+	return fetched
+}
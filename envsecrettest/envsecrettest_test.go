@@ -0,0 +1,303 @@
+package envsecrettest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/envsecrettest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(map[string]map[string]interface{}{
+		"db-creds": {"username": "user", "password": "pass"},
+		"api-key":  {"value": "abc123"},
+	})
+
+	spec := struct {
+		DB  envsecret.Login
+		API envsecret.String
+	}{
+		DB:  envsecret.NewLogin("db-creds"),
+		API: envsecret.NewString("api-key"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, store))
+	assert.Equal(t, "user", spec.DB.Username)
+	assert.Equal(t, "abc123", spec.API.Value)
+
+	assert.Equal(t, []string{"db-creds", "api-key"}, store.Calls())
+	assert.True(t, store.AssertFetched(t, "db-creds", "api-key"))
+	assert.True(t, store.AssertNotFetched(t, "other"))
+
+	store.Reset()
+	assert.Empty(t, store.Calls())
+
+	injected := errors.New("injected")
+	store.SetError("api-key", injected)
+	assert.Equal(t, injected, envsecret.Process(&spec, store))
+
+	store.SetError("api-key", nil)
+	assert.NoError(t, envsecret.Process(&spec, store))
+
+	_, err := store.Get("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+func TestMemoryStore_Assertions(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(nil)
+	_, _ = store.Get("fetched")
+
+	recorder := &recordingT{TB: t}
+	assert.False(t, store.AssertFetched(recorder, "fetched", "other"))
+	assert.False(t, store.AssertNotFetched(recorder, "fetched"))
+	assert.Equal(t, []string{
+		`expected secret "other" to have been fetched, fetched ["fetched"]`,
+		`expected secret "fetched" not to have been fetched`,
+	}, recorder.errors)
+}
+
+func TestMemoryStore_Latency(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(map[string]map[string]interface{}{
+		"slow": {"value": "slow"},
+		"fast": {"value": "fast"},
+	})
+	store.SetLatency("", 50*time.Millisecond)
+	store.SetLatency("fast", 0)
+
+	start := time.Now()
+	_, err := store.Get("fast")
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	_, err = store.Get("slow")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("secret-%d", i%5)
+			_ = store.Put(id, map[string]interface{}{"value": i})
+			store.SetError(id, nil)
+			store.SetLatency(id, 0)
+			_, _ = store.Get(id)
+			_ = store.Calls()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, store.Calls(), 20)
+}
+
+func TestNewMemoryStoreFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("db-creds:\n  username: user\n  password: pass\n"), 0o600))
+
+	store := envsecrettest.NewMemoryStoreFromFile(t, path)
+	actual, err := store.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"username": "user", "password": "pass"}, actual)
+}
+
+func TestProcess(t *testing.T) {
+	spec := struct {
+		Token    envsecret.String
+		Other    envsecret.String `secret_keys:"other"`
+		DB       envsecret.Login
+		Settings envsecret.Map `secret_keys:"a,b"`
+		Public   envsecret.PublicKey
+		Private  envsecret.PrivateKey
+		TLS      envsecret.Certificate
+		Tenants  envsecret.Prefix
+		Port     envsecret.Int `secret_keys:"port"`
+		Ratio    envsecret.Float64
+		Enabled  envsecret.Bool
+		Timeout  envsecret.Duration
+		Endpoint envsecret.URL
+		Ignored  envsecret.String `ignored:"true"`
+		Debug    bool
+	}{
+		Token:   envsecret.NewString("token"),
+		Other:   envsecret.NewString("token"),
+		Tenants: envsecret.NewPrefix("tenants/"),
+	}
+
+	store := envsecrettest.Process(t, &spec)
+
+	assert.Equal(t, envsecrettest.Value("token", "value"), spec.Token.Value)
+	assert.Equal(t, envsecrettest.Value("token", "other"), spec.Other.Value)
+	assert.Equal(t, "DB", spec.DB.ID())
+	assert.Equal(t, envsecrettest.Value("DB", "username"), spec.DB.Username)
+	assert.Equal(t, envsecrettest.Value("DB", "password"), spec.DB.Password)
+	assert.Equal(t, map[string]interface{}{
+		"a": envsecrettest.Value("Settings", "a"),
+		"b": envsecrettest.Value("Settings", "b"),
+	}, spec.Settings.Values)
+	assert.NotNil(t, spec.Public.Key)
+	assert.NotNil(t, spec.Private.Key)
+	assert.Equal(t, spec.Private.Key.PublicKey, *spec.Public.Key)
+	assert.NotEmpty(t, spec.TLS.Certificate.Certificate)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"tenants/example": {"value": envsecrettest.Value("tenants/example", "value")},
+	}, spec.Tenants.Values)
+	assert.Equal(t, 42, spec.Port.Value)
+	assert.Equal(t, 0.5, spec.Ratio.Value)
+	assert.True(t, spec.Enabled.Value)
+	assert.Equal(t, 30*time.Second, spec.Timeout.Value)
+	assert.Equal(t, "https://example.com/Endpoint", spec.Endpoint.Value.String())
+	assert.Equal(t, "", spec.Ignored.ID())
+
+	store.AssertFetched(t, "token", "DB", "Settings", "Public", "Private", "TLS", "tenants/example", "Port", "Endpoint")
+	store.AssertNotFetched(t, "Ignored")
+}
+
+func TestFaultStore(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(map[string]map[string]interface{}{
+		"db-creds": {"username": "user", "password": "pass"},
+		"api-key":  {"value": "abc123"},
+	})
+
+	get := func(subject *envsecrettest.FaultStore) (results []string) {
+		for i := 0; i < 50; i++ {
+			actual, err := subject.Get("db-creds")
+			results = append(results, fmt.Sprint(actual, err))
+		}
+		return
+	}
+	faults := envsecrettest.Faults{Seed: 42, ErrorRate: 0.3, CorruptRate: 0.3}
+	first, second := get(envsecrettest.NewFaultStore(store, faults)), get(envsecrettest.NewFaultStore(store, faults))
+	assert.Equal(t, first, second)
+	faults.Seed = 7
+	assert.NotEqual(t, first, get(envsecrettest.NewFaultStore(store, faults)))
+
+	injected := errors.New("injected")
+	subject := envsecrettest.NewFaultStore(store, envsecrettest.Faults{Errors: map[string]error{"api-key": injected}})
+	_, err := subject.Get("api-key")
+	assert.Equal(t, injected, err)
+	_, err = subject.Get("db-creds")
+	assert.NoError(t, err)
+
+	subject = envsecrettest.NewFaultStore(store, envsecrettest.Faults{ErrorRate: 1})
+	_, err = subject.Get("api-key")
+	assert.True(t, errors.Is(err, envsecrettest.ErrInjected))
+
+	subject = envsecrettest.NewFaultStore(store, envsecrettest.Faults{TimeoutRate: 1, Timeout: 20 * time.Millisecond})
+	start := time.Now()
+	_, err = subject.Get("api-key")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	subject = envsecrettest.NewFaultStore(store, envsecrettest.Faults{CorruptRate: 1})
+	actual, err := subject.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Len(t, actual, 1)
+	spec := struct {
+		DB envsecret.Login
+	}{
+		DB: envsecret.NewLogin("db-creds"),
+	}
+	assert.Error(t, envsecret.Process(&spec, subject))
+
+	actual, err = subject.Get("api-key")
+	assert.NoError(t, err)
+	assert.Len(t, actual, 1)
+	assert.NotEqual(t, "abc123", actual["value"])
+
+	_, err = subject.Get("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+func TestFaultStore_Latency(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(map[string]map[string]interface{}{"api-key": {"value": "abc123"}})
+	subject := envsecrettest.NewFaultStore(store, envsecrettest.Faults{
+		Latency: 20 * time.Millisecond,
+		Jitter:  20 * time.Millisecond,
+	})
+
+	start := time.Now()
+	_, err := subject.Get("api-key")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestRecorder(t *testing.T) {
+	store := envsecrettest.NewMemoryStore(map[string]map[string]interface{}{
+		"db-creds": {"username": "user", "password": "pass"},
+		"config":   {"port": 8080.0, "debug": true, "hosts": []interface{}{"a", "b"}},
+	})
+
+	recorder := envsecrettest.NewRecorder(store)
+	spec := struct {
+		DB envsecret.Login
+	}{
+		DB: envsecret.NewLogin("db-creds"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, recorder))
+	_, err := recorder.Get("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.Equal(t, envsecret.Fixtures{"db-creds": {"username": "user", "password": "pass"}}, recorder.Fixtures())
+
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	assert.NoError(t, recorder.Save(path))
+
+	spec.DB = envsecret.NewLogin("db-creds")
+	replay := envsecrettest.NewReplayStoreFromFile(t, path)
+	assert.NoError(t, envsecret.Process(&spec, replay))
+	assert.Equal(t, "user", spec.DB.Username)
+	assert.True(t, replay.AssertFetched(t, "db-creds"))
+
+	recorder = envsecrettest.NewRecorder(store, envsecrettest.FakeValues())
+	actual, err := recorder.Get("config")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080.0, actual["port"])
+
+	fixtures := recorder.Fixtures()
+	assert.IsType(t, 0.0, fixtures["config"]["port"])
+	assert.IsType(t, true, fixtures["config"]["debug"])
+	assert.Equal(t, []interface{}{envsecrettest.Value("config", "hosts.0"), envsecrettest.Value("config", "hosts.1")}, fixtures["config"]["hosts"])
+
+	again := envsecrettest.NewRecorder(store, envsecrettest.FakeValues())
+	_, _ = again.Get("config")
+	assert.Equal(t, fixtures, again.Fixtures())
+}
+
+func TestReplayStore_Unexpected(t *testing.T) {
+	recorder := &recordingT{TB: t}
+	replay := envsecrettest.NewReplayStore(recorder, envsecret.Fixtures{"api-key": {"value": "abc123"}})
+
+	actual, err := replay.Get("api-key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "abc123"}, actual)
+	assert.Empty(t, recorder.errors)
+
+	_, err = replay.Get("other")
+	assert.True(t, errors.Is(err, envsecrettest.ErrUnexpected))
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.Equal(t, []string{`unexpected secret "other"`}, recorder.errors)
+}
+
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Helper() {}
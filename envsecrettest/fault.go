@@ -0,0 +1,141 @@
+package envsecrettest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// ErrInjected is wrapped by the errors a FaultStore injects at its error rate.
+var ErrInjected = errors.New("injected fault")
+
+// Faults configures the faults injected by a FaultStore. Rates are probabilities between 0 and 1
+// drawn independently for each call to Get.
+type Faults struct {
+	// Seed seeds the random number generator, so the same sequence of calls sees the same faults.
+	Seed int64
+	// Latency delays every call to Get.
+	Latency time.Duration
+	// Jitter adds a random delay of up to Jitter to every call to Get.
+	Jitter time.Duration
+	// ErrorRate is the probability of Get failing with an error wrapping ErrInjected.
+	ErrorRate float64
+	// Errors are returned by Get for the given identifiers, on every call.
+	Errors map[string]error
+	// TimeoutRate is the probability of Get blocking for Timeout and then failing with an error
+	// wrapping context.DeadlineExceeded.
+	TimeoutRate float64
+	// Timeout is how long Get blocks before timing out.
+	Timeout time.Duration
+	// CorruptRate is the probability of Get returning a corrupt secret, with one of several
+	// entries missing and every remaining value replaced by random bytes.
+	CorruptRate float64
+}
+
+// FaultStore wraps an envsecret.Store, injecting latency, errors, timeouts and corrupt secrets
+// to test how code using envsecret copes with an unreliable backend. It only implements
+// envsecret.Store, so Process gets each secret separately. It is safe for concurrent use, though
+// concurrent calls draw from the random number generator in no particular order.
+type FaultStore struct {
+	store  envsecret.Store
+	faults Faults
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFaultStore returns a FaultStore injecting the given faults into calls to the store.
+func NewFaultStore(store envsecret.Store, faults Faults) *FaultStore {
+	errs := make(map[string]error, len(faults.Errors))
+	for id, err := range faults.Errors {
+		errs[id] = err
+	}
+	faults.Errors = errs
+
+	return &FaultStore{
+		store:  store,
+		faults: faults,
+		rand:   rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+// draw holds the random outcomes of a single call to Get.
+type draw struct {
+	jitter                 time.Duration
+	timeout, fail, corrupt bool
+	seed                   int64
+}
+
+// Get implements envsecret.Store, applying the configured faults before or after getting the
+// secret from the wrapped store.
+func (s *FaultStore) Get(id string) (map[string]interface{}, error) {
+	d := s.draw()
+
+	time.Sleep(s.faults.Latency + d.jitter)
+
+	if err, found := s.faults.Errors[id]; found {
+		return nil, err
+	} else if d.timeout {
+		time.Sleep(s.faults.Timeout)
+		return nil, fmt.Errorf("%w: %s", context.DeadlineExceeded, id)
+	} else if d.fail {
+		return nil, fmt.Errorf("%w: %s", ErrInjected, id)
+	}
+
+	secret, err := s.store.Get(id)
+	if err != nil || !d.corrupt {
+		return secret, err
+	}
+
+	return corrupt(secret, rand.New(rand.NewSource(d.seed))), nil
+}
+
+// draw the outcomes of a call to Get. Every outcome is drawn on every call, so that the faults
+// seen by later calls do not depend on those seen by earlier ones.
+func (s *FaultStore) draw() draw {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var d draw
+	if s.faults.Jitter > 0 {
+		d.jitter = time.Duration(s.rand.Int63n(int64(s.faults.Jitter)))
+	} else {
+		s.rand.Int63()
+	}
+	d.timeout = s.rand.Float64() < s.faults.TimeoutRate
+	d.fail = s.rand.Float64() < s.faults.ErrorRate
+	d.corrupt = s.rand.Float64() < s.faults.CorruptRate
+	d.seed = s.rand.Int63()
+
+	return d
+}
+
+// corrupt returns a copy of the secret with one entry removed, should there be several, and
+// every remaining value replaced by random bytes.
+func corrupt(secret map[string]interface{}, r *rand.Rand) map[string]interface{} {
+	keys := make([]string, 0, len(secret))
+	for k := range secret {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 1 {
+		i := r.Intn(len(keys))
+		keys = append(keys[:i], keys[i+1:]...)
+	}
+
+	corrupted := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		b := make([]byte, 8+r.Intn(24))
+		r.Read(b)
+		corrupted[k] = string(b)
+	}
+
+	return corrupted
+}
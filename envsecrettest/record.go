@@ -0,0 +1,186 @@
+package envsecrettest
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"testing"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// ErrUnexpected is wrapped by the error a ReplayStore returns for an identifier it was not
+// recorded with.
+var ErrUnexpected = errors.New("unexpected secret")
+
+// RecordOption configures a Recorder.
+type RecordOption func(*Recorder)
+
+// FakeValues makes a Recorder record deterministic fake values in place of the secrets it
+// retrieves, preserving their shape. Strings are given by Value, with nested keys and list
+// indices joined by ".", and numbers and booleans are derived from the same. Secrets parsed
+// from strings, such as durations, keys and certificates, will not decode from fake values.
+func FakeValues() RecordOption {
+	return func(r *Recorder) { r.fake = true }
+}
+
+// Recorder wraps an envsecret.Store, recording the secret returned by every successful call to
+// Get so that it can be saved as fixtures for a ReplayStore. It is safe for concurrent use.
+type Recorder struct {
+	store envsecret.Store
+	fake  bool
+
+	mu       sync.Mutex
+	fixtures envsecret.Fixtures
+}
+
+// NewRecorder returns a Recorder recording the secrets retrieved from the store.
+func NewRecorder(store envsecret.Store, opts ...RecordOption) *Recorder {
+	r := &Recorder{
+		store:    store,
+		fixtures: make(envsecret.Fixtures),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Get implements envsecret.Store, recording the secret retrieved from the wrapped store. The
+// real secret is returned even if fake values are recorded.
+func (r *Recorder) Get(id string) (map[string]interface{}, error) {
+	secret, err := r.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]interface{}, len(secret))
+	for k, v := range secret {
+		if r.fake {
+			recorded[k] = fake(Value(id, k), v)
+		} else {
+			recorded[k] = clone(v)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fixtures[id] = recorded
+
+	return secret, nil
+}
+
+// Fixtures returns the secrets recorded so far, keyed by identifier.
+func (r *Recorder) Fixtures() envsecret.Fixtures {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fixtures := make(envsecret.Fixtures, len(r.fixtures))
+	for id, secret := range r.fixtures {
+		fixtures[id] = clone(secret).(map[string]interface{})
+	}
+
+	return fixtures
+}
+
+// Save writes the secrets recorded so far to the JSON or YAML file at the given path, as
+// written by envsecret.WriteFixtures.
+func (r *Recorder) Save(path string) error {
+	return envsecret.WriteFixtures(path, r.Fixtures())
+}
+
+// ReplayStore is a MemoryStore serving recorded fixtures, which fails the test when asked for a
+// secret it does not hold.
+type ReplayStore struct {
+	*MemoryStore
+	t testing.TB
+}
+
+// NewReplayStore returns a ReplayStore serving the given fixtures.
+func NewReplayStore(t testing.TB, fixtures envsecret.Fixtures) *ReplayStore {
+	return &ReplayStore{
+		MemoryStore: NewMemoryStore(fixtures),
+		t:           t,
+	}
+}
+
+// NewReplayStoreFromFile returns a ReplayStore serving the fixtures in the JSON or YAML file at
+// the given path, such as one saved by a Recorder, failing the test should it be invalid.
+func NewReplayStoreFromFile(t testing.TB, path string) *ReplayStore {
+	t.Helper()
+
+	return &ReplayStore{
+		MemoryStore: NewMemoryStoreFromFile(t, path),
+		t:           t,
+	}
+}
+
+// Get implements envsecret.Store, reporting a test error and returning an error wrapping both
+// ErrUnexpected and envsecret.ErrNotFound for identifiers without a fixture.
+func (s *ReplayStore) Get(id string) (map[string]interface{}, error) {
+	secret, err := s.MemoryStore.Get(id)
+	if errors.Is(err, envsecret.ErrNotFound) {
+		s.t.Errorf("unexpected secret %q", id)
+		return nil, fmt.Errorf("%w: %w", ErrUnexpected, err)
+	}
+
+	return secret, err
+}
+
+// fake returns a deterministic fake value of the same type as v, derived from the given name.
+func fake(name string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = fake(name+"."+k, x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = fake(fmt.Sprintf("%s.%d", name, i), x)
+		}
+		return l
+	case bool:
+		return hash(name)%2 == 0
+	case float64:
+		return float64(hash(name) % 1000)
+	case int:
+		return int(hash(name) % 1000)
+	case nil:
+		return nil
+	default:
+		return name
+	}
+}
+
+func hash(name string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return h.Sum32()
+}
+
+// clone returns a deep copy of nested maps and lists, so that recorded secrets are unaffected
+// by later changes to those returned.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = clone(x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = clone(x)
+		}
+		return l
+	default:
+		return v
+	}
+}
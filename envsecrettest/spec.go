@@ -0,0 +1,222 @@
+package envsecrettest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// Value returns the fake value Fixtures gives the given key of the secret with the given
+// identifier, for tests to compare populated secrets against.
+func Value(id, key string) string {
+	return id + ":" + key
+}
+
+// Process populates every secret in the spec from fake fixtures, as built by Fixtures, failing
+// the test should envsecret.Process fail. Secrets without an identifier are first given their
+// field name as identifier, so that the spec is fully populated. The MemoryStore serving the
+// fixtures is returned for further assertions.
+func Process(t testing.TB, spec interface{}) *MemoryStore {
+	t.Helper()
+
+	V := reflect.ValueOf(spec)
+	if V.Kind() != reflect.Ptr || V.Elem().Kind() != reflect.Struct {
+		t.Fatalf("processing spec: %v", envsecret.ErrRequiresStructPtr)
+	}
+	V = V.Elem()
+
+	for i := 0; i < V.NumField(); i++ {
+		if secret := secretFrom(V, i); secret != nil && secret.ID() == "" {
+			if base := V.Field(i).FieldByName("Base"); base.IsValid() && base.Type() == reflect.TypeOf(envsecret.Base{}) {
+				_ = base.Addr().Interface().(*envsecret.Base).Decode(V.Type().Field(i).Name)
+			}
+		}
+	}
+
+	store := NewMemoryStore(Fixtures(spec))
+	if err := envsecret.Process(spec, store); err != nil {
+		t.Fatalf("processing spec: %v", err)
+	}
+
+	return store
+}
+
+// Fixtures builds fake secret values for every secret in the spec, keyed by identifier, that
+// decode into the spec's secret types and honor their secret_keys. String values are given by
+// Value, and keys and certificates are generated once per test binary. Int, Float64, Bool and
+// Duration secrets are given 42, 0.5, true and 30 seconds, and a URL is given one beneath
+// https://example.com/ ending in its identifier. A Prefix is given a single secret, the prefix
+// followed by "example".
+func Fixtures(spec interface{}) envsecret.Fixtures {
+	fixtures := make(envsecret.Fixtures)
+
+	V := reflect.Indirect(reflect.ValueOf(spec))
+	if V.Kind() != reflect.Struct {
+		return fixtures
+	}
+
+	for i := 0; i < V.NumField(); i++ {
+		secret := secretFrom(V, i)
+		if secret == nil || secret.ID() == "" {
+			continue
+		}
+
+		var (
+			id   = secret.ID()
+			keys []string
+		)
+		if tag := V.Type().Field(i).Tag.Get("secret_keys"); tag != "" {
+			keys = strings.Split(tag, ",")
+		}
+
+		var m map[string]interface{}
+		switch secret.(type) {
+		case *envsecret.Login:
+			m = values(id, []string{"username", "password"})
+		case *envsecret.PublicKey:
+			m = map[string]interface{}{key(keys, "public_key"): generated().publicKey}
+		case *envsecret.PrivateKey:
+			m = map[string]interface{}{key(keys, "private_key"): generated().privateKey}
+		case *envsecret.Certificate:
+			m = map[string]interface{}{"certificate": generated().certificate, "private_key": generated().certificateKey}
+		case *envsecret.Int:
+			m = map[string]interface{}{key(keys, "value"): 42}
+		case *envsecret.Float64:
+			m = map[string]interface{}{key(keys, "value"): 0.5}
+		case *envsecret.Bool:
+			m = map[string]interface{}{key(keys, "value"): true}
+		case *envsecret.Duration:
+			m = map[string]interface{}{key(keys, "value"): "30s"}
+		case *envsecret.URL:
+			m = map[string]interface{}{key(keys, "value"): "https://example.com/" + url.PathEscape(id)}
+		case *envsecret.Prefix:
+			id += "example"
+			m = values(id, keysOr(keys, "value"))
+		default:
+			m = values(id, keysOr(keys, "value"))
+		}
+
+		if fixtures[id] == nil {
+			fixtures[id] = make(map[string]interface{})
+		}
+		for k, v := range m {
+			fixtures[id][k] = v
+		}
+	}
+
+	return fixtures
+}
+
+// secretFrom returns the i-th field of the struct as a Secret, if it is one and not ignored.
+func secretFrom(V reflect.Value, i int) envsecret.Secret {
+	if V.Type().Field(i).Tag.Get("ignored") == "true" {
+		return nil
+	}
+
+	field := V.Field(i)
+	if !field.CanAddr() || !field.Addr().CanInterface() {
+		return nil
+	}
+
+	secret, _ := field.Addr().Interface().(envsecret.Secret)
+	return secret
+}
+
+// values maps each key to its fake value.
+func values(id string, keys []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		m[k] = Value(id, k)
+	}
+
+	return m
+}
+
+// key returns the first of the keys, or else def.
+func key(keys []string, def string) string {
+	return keysOr(keys, def)[0]
+}
+
+// keysOr returns the keys, or else def alone.
+func keysOr(keys []string, def string) []string {
+	if len(keys) == 0 {
+		return []string{def}
+	}
+	return keys
+}
+
+// material holds generated keys and certificates as base64 encoded PEM.
+type material struct {
+	publicKey      string
+	privateKey     string
+	certificate    string
+	certificateKey string
+}
+
+var (
+	generateOnce sync.Once
+	generatedMat material
+)
+
+// generated returns key material, generating it on first use.
+func generated() material {
+	generateOnce.Do(func() {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(fmt.Sprintf("generating RSA key: %v", err))
+		}
+		publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		if err != nil {
+			panic(fmt.Sprintf("marshaling RSA public key: %v", err))
+		}
+
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(fmt.Sprintf("generating ECDSA key: %v", err))
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "envsecrettest"},
+			DNSNames:     []string{"localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, template, template, &ecKey.PublicKey, ecKey)
+		if err != nil {
+			panic(fmt.Sprintf("creating certificate: %v", err))
+		}
+		ecDER, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			panic(fmt.Sprintf("marshaling ECDSA key: %v", err))
+		}
+
+		generatedMat = material{
+			publicKey:      encode("PUBLIC KEY", publicDER),
+			privateKey:     encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			certificate:    encode("CERTIFICATE", certDER),
+			certificateKey: encode("EC PRIVATE KEY", ecDER),
+		}
+	})
+
+	return generatedMat
+}
+
+// encode DER bytes as base64 encoded PEM, as the envsecret key and certificate types expect.
+func encode(blockType string, der []byte) string {
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
@@ -2,8 +2,11 @@ package envsecret
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 const tag = "secret_keys"
@@ -13,16 +16,107 @@ var (
 	ErrRequiresStructPtr = errors.New("requires a pointer to a config specification struct")
 	ErrMaxOneKey         = errors.New("secret type requires at most one override key")
 	ErrNoOverride        = errors.New("secret type does not allow key overrides")
+	ErrNotFound          = errors.New("secret not found")
+	ErrNotListable       = errors.New("secret type requires a store implementing ListStore")
 )
 
-type cacheMap map[string]map[string]interface{}
-
 // Store of secrets.
 type Store interface {
 	// Get should return the map of secret values for the given identifier.
 	Get(string) (map[string]interface{}, error)
 }
 
+// BatchStore is a Store able to retrieve many secrets at once. Process uses GetMany
+// when the given Store implements it.
+type BatchStore interface {
+	Store
+	// GetMany should return the map of secret values for each of the given identifiers,
+	// keyed by identifier. Failures for individual identifiers should be reported with a
+	// BatchError alongside the secrets that were retrieved.
+	GetMany([]string) (map[string]map[string]interface{}, error)
+}
+
+// WritableStore is a Store able to create, replace and remove secrets, e.g. to seed local
+// environments and tests. See Seed.
+type WritableStore interface {
+	Store
+	// Put should create the secret with the given identifier, or replace its value should it
+	// already exist, such that Get subsequently returns the given entries. Some stores return
+	// entries of their own alongside them, such as the metadata of Vault KV version 2 secrets.
+	Put(string, map[string]interface{}) error
+	// Delete should remove the secret with the given identifier, returning an error wrapping
+	// ErrNotFound if it does not exist.
+	Delete(string) error
+}
+
+// ListStore is a Store able to list the identifiers of its secrets. Process requires it to
+// populate Prefix secrets.
+type ListStore interface {
+	Store
+	// List should return the identifier of every secret beneath the given prefix, in a form
+	// accepted by Get.
+	List(string) ([]string, error)
+}
+
+// Metadata describes the version of a secret retrieved from a MetadataStore. Fields the store
+// cannot provide are left zero.
+type Metadata struct {
+	// Version identifies the version of the secret, e.g. a Secrets Manager version ID.
+	Version string
+	// Stage labels the version, e.g. AWSCURRENT.
+	Stage string
+	// Created is when the version was created.
+	Created time.Time
+	// Updated is when the secret was last modified, e.g. a Vault KV version 2 secret.
+	Updated time.Time
+	// LeaseTTL is the duration of the secret's lease, e.g. for Vault dynamic secrets.
+	LeaseTTL time.Duration
+	// Expires is when the secret or its lease expires.
+	Expires time.Time
+}
+
+// MetadataStore is a Store able to describe the version of the secrets it returns. Process
+// uses GetMetadata when the given Store implements it, and makes the metadata available through
+// the secret's Base, e.g. Version and ExpiresAt.
+type MetadataStore interface {
+	Store
+	// GetMetadata should behave as Get, additionally returning the secret's metadata.
+	GetMetadata(string) (map[string]interface{}, Metadata, error)
+}
+
+// BatchMetadataStore is a BatchStore able to describe the version of the secrets it returns.
+// Process uses GetManyMetadata in place of GetMany when the given Store implements it.
+type BatchMetadataStore interface {
+	BatchStore
+	// GetManyMetadata should behave as GetMany, additionally returning the metadata of each
+	// secret retrieved, keyed by identifier.
+	GetManyMetadata([]string) (map[string]map[string]interface{}, map[string]Metadata, error)
+}
+
+// metadataSetter is implemented by secrets embedding Base, through which Process sets metadata.
+type metadataSetter interface {
+	setMetadata(Metadata)
+}
+
+// BatchError reports the failure to retrieve individual secrets from a BatchStore, by identifier.
+type BatchError map[string]error
+
+// Error implements error.
+func (e BatchError) Error() string {
+	ids := make([]string, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%s: %v", id, e[id])
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
 // MustProcess calls Process and panics on any error.
 func MustProcess(spec interface{}, store Store) {
 	if err := Process(spec, store); err != nil {
@@ -42,7 +136,10 @@ func Process(spec interface{}, store Store) error {
 		return ErrRequiresStructPtr
 	}
 
-	cache := make(cacheMap)
+	cache, errs, err := prefetch(V, store)
+	if err != nil {
+		return err
+	}
 
 	for i := 0; i < V.NumField(); i++ {
 		field := V.Type().Field(i)
@@ -63,17 +160,29 @@ func Process(spec interface{}, store Store) error {
 
 			allowList := parseAllowList(field)
 			switch secret.(type) {
-			case *String, *PublicKey, *PrivateKey:
+			case *String, *Int, *Float64, *Bool, *Duration, *URL, *PublicKey, *PrivateKey:
 				if len(allowList) > 1 {
 					return ErrMaxOneKey
 				}
-			case *Login:
+			case *Login, *Certificate:
 				if len(allowList) > 0 {
 					return ErrNoOverride
 				}
 			}
 
-			val, err := get(secret, store, allowList, cache)
+			if err, failed := errs[secret.ID()]; failed {
+				return err
+			}
+
+			var (
+				val      map[string]interface{}
+				metadata Metadata
+			)
+			if _, ok := secret.(*Prefix); ok {
+				val, err = getPrefix(secret.ID(), store, allowList, cache)
+			} else {
+				val, metadata, err = get(secret.ID(), store, allowList, cache)
+			}
 			if err != nil {
 				return err
 			}
@@ -81,6 +190,10 @@ func Process(spec interface{}, store Store) error {
 			if err := secret.Decode(val); err != nil {
 				return err
 			}
+
+			if m, ok := secret.(metadataSetter); ok {
+				m.setMetadata(metadata)
+			}
 		}
 		// TODO Process recursively to support nested structs.
 	}
@@ -88,18 +201,143 @@ func Process(spec interface{}, store Store) error {
 	return nil
 }
 
-// get the requested secret from the store and filters the results.
-func get(s Secret, store Store, allowList []string, cache cacheMap) (v map[string]interface{}, e error) {
-	v, cached := cache[s.ID()]
+// cache of the secrets retrieved by Process and their metadata, keyed by identifier.
+type cache struct {
+	values   map[string]map[string]interface{}
+	metadata map[string]Metadata
+}
+
+func newCache() *cache {
+	return &cache{
+		values:   make(map[string]map[string]interface{}),
+		metadata: make(map[string]Metadata),
+	}
+}
+
+// prefetch retrieves every secret in the spec with a single call when the store is a BatchStore,
+// returning the populated cache and any errors for individual identifiers.
+func prefetch(V reflect.Value, store Store) (*cache, BatchError, error) {
+	if _, ok := store.(BatchStore); !ok {
+		return newCache(), nil, nil
+	}
+
+	var (
+		ids  []string
+		seen = make(map[string]bool)
+	)
+	for i := 0; i < V.NumField(); i++ {
+		if V.Type().Field(i).Tag.Get("ignored") == "true" {
+			continue
+		}
+
+		secret := secretFrom(V.Field(i))
+		if _, prefix := secret.(*Prefix); prefix {
+			continue
+		}
+
+		if secret != nil && secret.ID() != "" && !seen[secret.ID()] {
+			seen[secret.ID()] = true
+			ids = append(ids, secret.ID())
+		}
+	}
+
+	c := newCache()
+	if len(ids) == 0 {
+		return c, nil, nil
+	}
+
+	err := c.getMany(ids, store.(BatchStore))
+	errs, partial := err.(BatchError)
+	if err != nil && !partial {
+		return nil, nil, err
+	}
+
+	return c, errs, nil
+}
+
+// getMany retrieves the secrets from the batch store into the cache, along with their metadata
+// if the store is a BatchMetadataStore.
+func (c *cache) getMany(ids []string, store BatchStore) error {
+	var (
+		values   map[string]map[string]interface{}
+		metadata map[string]Metadata
+		err      error
+	)
+	if m, ok := store.(BatchMetadataStore); ok {
+		values, metadata, err = m.GetManyMetadata(ids)
+	} else {
+		values, err = store.GetMany(ids)
+	}
+
+	for id, v := range values {
+		c.values[id] = v
+		c.metadata[id] = metadata[id]
+	}
+
+	return err
+}
+
+// get the requested secret from the store, along with its metadata if the store is a
+// MetadataStore, and filters the results.
+func get(id string, store Store, allowList []string, c *cache) (map[string]interface{}, Metadata, error) {
+	v, cached := c.values[id]
 	if !cached {
-		v, e = store.Get(s.ID())
-		if e != nil {
-			return
+		var (
+			metadata Metadata
+			err      error
+		)
+		if m, ok := store.(MetadataStore); ok {
+			v, metadata, err = m.GetMetadata(id)
+		} else {
+			v, err = store.Get(id)
+		}
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		c.values[id], c.metadata[id] = v, metadata
+	}
+
+	return filter(v, allowList), c.metadata[id], nil
+}
+
+// getPrefix lists the secrets beneath the prefix and gets each of them, with a single call
+// when the store is a BatchStore, returning the filtered results keyed by identifier.
+func getPrefix(prefix string, store Store, allowList []string, c *cache) (map[string]interface{}, error) {
+	lister, ok := store.(ListStore)
+	if !ok {
+		return nil, ErrNotListable
+	}
+
+	ids, err := lister.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if batch, ok := store.(BatchStore); ok {
+		var missing []string
+		for _, id := range ids {
+			if _, cached := c.values[id]; !cached {
+				missing = append(missing, id)
+			}
+		}
+
+		if len(missing) > 0 {
+			if err := c.getMany(missing, batch); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	m := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		v, _, err := get(id, store, allowList, c)
+		if err != nil {
+			return nil, err
 		}
-		cache[s.ID()] = v
+		m[id] = v
 	}
 
-	return filter(v, allowList), nil
+	return m, nil
 }
 
 // filter the map according to the allowList, if provided.
@@ -0,0 +1,86 @@
+package envsecret
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures map secret identifiers to their secret values.
+type Fixtures map[string]map[string]interface{}
+
+// ReadFixtures reads fixtures from the JSON or YAML file at the given path. Files with a .yaml
+// or .yml extension are parsed as YAML and any other file as JSON.
+func ReadFixtures(path string) (Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &fixtures)
+	default:
+		err = json.Unmarshal(b, &fixtures)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return fixtures, nil
+}
+
+// WriteFixtures writes the fixtures to the file at the given path, as YAML for files with a
+// .yaml or .yml extension and as indented JSON otherwise, such that ReadFixtures reads them back.
+func WriteFixtures(path string, fixtures Fixtures) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		b, err = yaml.Marshal(fixtures)
+	default:
+		if b, err = json.MarshalIndent(fixtures, "", "  "); err == nil {
+			b = append(b, '\n')
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+
+	return os.WriteFile(path, b, 0o600)
+}
+
+// Seed puts every fixture into the store, in order of identifier.
+func Seed(store WritableStore, fixtures Fixtures) error {
+	ids := make([]string, 0, len(fixtures))
+	for id := range fixtures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := store.Put(id, fixtures[id]); err != nil {
+			return fmt.Errorf("seeding %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// SeedFile reads fixtures from the JSON or YAML file at the given path and puts them into the store.
+func SeedFile(store WritableStore, path string) error {
+	fixtures, err := ReadFixtures(path)
+	if err != nil {
+		return err
+	}
+
+	return Seed(store, fixtures)
+}
@@ -0,0 +1,100 @@
+package envsecret_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/memory"
+)
+
+func TestWriteFixtures(t *testing.T) {
+	fixtures := envsecret.Fixtures{
+		"db-creds": {"username": "user", "password": "pass"},
+		"config":   {"port": 8080.0, "debug": true, "nested": map[string]interface{}{"key": "value"}},
+	}
+
+	for _, name := range []string{"secrets.json", "secrets.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			assert.NoError(t, envsecret.WriteFixtures(path, fixtures))
+
+			actual, err := envsecret.ReadFixtures(path)
+			assert.NoError(t, err)
+			if name == "secrets.yaml" {
+				actual["config"]["port"] = float64(actual["config"]["port"].(int))
+			}
+			assert.Equal(t, fixtures, actual)
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	store := &failingWritableStore{MemoryStore: memory.New(nil), fail: "c"}
+
+	err := envsecret.Seed(store, envsecret.Fixtures{
+		"b": {"value": "2"},
+		"a": {"value": "1"},
+		"c": {"value": "3"},
+		"d": {"value": "4"},
+	})
+	assert.EqualError(t, err, "seeding c: put failed")
+	assert.Equal(t, []string{"a", "b", "c"}, store.puts)
+
+	_, err = store.Get("d")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+func TestSeedFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fixtures.json": `{"db": {"username": "user", "password": "pass"}, "token": {"value": "abc"}}`,
+		"fixtures.yaml": "db:\n  username: user\n  password: pass\ntoken:\n  value: abc\n",
+	}
+
+	for name, contents := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+			store := memory.New(nil)
+			assert.NoError(t, envsecret.SeedFile(store, path))
+
+			spec := struct {
+				DB    envsecret.Login
+				Token envsecret.String
+			}{
+				DB:    envsecret.NewLogin("db"),
+				Token: envsecret.NewString("token"),
+			}
+			assert.NoError(t, envsecret.Process(&spec, store))
+			assert.Equal(t, "user", spec.DB.Username)
+			assert.Equal(t, "pass", spec.DB.Password)
+			assert.Equal(t, "abc", spec.Token.Value)
+		})
+	}
+
+	assert.Error(t, envsecret.SeedFile(memory.New(nil), filepath.Join(dir, "missing.json")))
+
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte(`["not", "fixtures"]`), 0o600))
+	assert.Error(t, envsecret.SeedFile(memory.New(nil), invalid))
+}
+
+type failingWritableStore struct {
+	*memory.MemoryStore
+	fail string
+	puts []string
+}
+
+func (s *failingWritableStore) Put(id string, secret map[string]interface{}) error {
+	s.puts = append(s.puts, id)
+	if id == s.fail {
+		return errors.New("put failed")
+	}
+	return s.MemoryStore.Put(id, secret)
+}
@@ -0,0 +1,154 @@
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// IdentityFileEnv names the environment variable holding the path of the age identity file
+// used by Open to decrypt a bundle.
+const IdentityFileEnv = "ENVSECRET_AGE_IDENTITY_FILE"
+
+// Bundle maps secret identifiers to their secret values.
+type Bundle map[string]map[string]interface{}
+
+// IDs returns the bundle's identifiers in sorted order.
+func (b Bundle) IDs() []string {
+	ids := make([]string, 0, len(b))
+	for id := range b {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Encrypt writes the bundle to w as an ASCII armored age file encrypted to the given recipients.
+func (b Bundle) Encrypt(w io.Writer, recipients ...age.Recipient) error {
+	plaintext, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	aw := armor.NewWriter(w)
+	ew, err := age.Encrypt(aw, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ew.Write(plaintext); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+
+	return aw.Close()
+}
+
+// Decrypt reads a bundle from an armored or binary age file using the given identities.
+func Decrypt(r io.Reader, identities ...age.Identity) (Bundle, error) {
+	br := bufio.NewReader(r)
+	if header, _ := br.Peek(len(armor.Header)); string(header) == armor.Header {
+		r = armor.NewReader(br)
+	} else {
+		r = br
+	}
+
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Bundle
+	if err := json.NewDecoder(dr).Decode(&b); err != nil {
+		return nil, fmt.Errorf("decoding bundle: %w", err)
+	}
+
+	return b, nil
+}
+
+// ReadIdentities parses the age identity file at the given path.
+func ReadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return age.ParseIdentities(f)
+}
+
+// ReadRecipients parses the age recipients file at the given path.
+func ReadRecipients(path string) ([]age.Recipient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return age.ParseRecipients(f)
+}
+
+// AgeStore serves secrets from a decrypted age bundle.
+type AgeStore struct {
+	bundle Bundle
+}
+
+// New returns an AgeStore serving secrets from the given bundle.
+func New(bundle Bundle) *AgeStore {
+	return &AgeStore{
+		bundle: bundle,
+	}
+}
+
+// Open decrypts the bundle at the given path with the identity file named by the
+// ENVSECRET_AGE_IDENTITY_FILE environment variable and returns an AgeStore serving it.
+func Open(path string) (*AgeStore, error) {
+	identityFile := os.Getenv(IdentityFileEnv)
+	if identityFile == "" {
+		return nil, fmt.Errorf("%s is not set", IdentityFileEnv)
+	}
+
+	identities, err := ReadIdentities(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading identities: %w", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := Decrypt(bytes.NewReader(b), identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", path, err)
+	}
+
+	return New(bundle), nil
+}
+
+// Get returns the secret stored in the bundle under the given identifier.
+func (s *AgeStore) Get(id string) (map[string]interface{}, error) {
+	v, found := s.bundle[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	m := make(map[string]interface{}, len(v))
+	for key, value := range v {
+		m[key] = value
+	}
+
+	return m, nil
+}
@@ -0,0 +1,132 @@
+package age_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/age"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestAgeStore_Get(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	identityFile := filepath.Join(dir, "identity.txt")
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := secretstore.Bundle{
+		"db-creds": {"username": "user", "password": "pass"},
+		"api-key":  {"value": "abc123"},
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Encrypt(&buf, identity.Recipient()); err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(dir, "secrets.age")
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(secretstore.IdentityFileEnv, identityFile)
+
+	subject, err := secretstore.Open(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "map",
+			id:       "db-creds",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "single value",
+			id:       "api-key",
+			expected: map[string]interface{}{"value": "abc123"},
+		},
+		{
+			name: "missing",
+			id:   "missing",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestAgeStore_Conformance(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		var buf bytes.Buffer
+		if err := secretstore.Bundle(fixtures).Encrypt(&buf, identity.Recipient()); err != nil {
+			t.Fatal(err)
+		}
+		bundle, err := secretstore.Decrypt(&buf, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secretstore.New(bundle)
+	})
+}
+
+func TestOpen_Errors(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+
+	dir := t.TempDir()
+	identityFile := filepath.Join(dir, "identity.txt")
+	if err := os.WriteFile(identityFile, []byte(identity.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (secretstore.Bundle{}).Encrypt(&buf, other.Recipient()); err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(dir, "secrets.age")
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(secretstore.IdentityFileEnv, "")
+	_, err := secretstore.Open(bundlePath)
+	assert.Error(t, err, "identity file unset")
+
+	t.Setenv(secretstore.IdentityFileEnv, identityFile)
+	_, err = secretstore.Open(bundlePath)
+	assert.Error(t, err, "bundle encrypted to another recipient")
+}
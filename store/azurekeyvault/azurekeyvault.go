@@ -0,0 +1,285 @@
+package azurekeyvault
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// apiVersion of the Key Vault REST API used for requests.
+const apiVersion = "7.4"
+
+// Content types of the secrets backing Key Vault certificates.
+const (
+	contentTypePEM    = "application/x-pem-file"
+	contentTypePKCS12 = "application/x-pkcs12"
+)
+
+// TokenCredential provides bearer tokens for Key Vault requests. Implementations are expected to
+// cache and refresh tokens as required, e.g. by wrapping an azidentity credential.
+type TokenCredential interface {
+	// Token should return an access token for the https://vault.azure.net scope.
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenCredential always returning the same token.
+type StaticToken string
+
+// Token implements TokenCredential.
+func (t StaticToken) Token(context.Context) (string, error) { return string(t), nil }
+
+// KeyVault provides access to Azure Key Vault.
+type KeyVault struct {
+	vaultURL   string
+	credential TokenCredential
+	client     *http.Client
+	ctx        context.Context
+}
+
+// New returns a KeyVault instance for the vault at the given URL, e.g.
+// "https://myvault.vault.azure.net", authenticating with the given credential.
+func New(vaultURL string, credential TokenCredential) *KeyVault {
+	return NewWithClient(vaultURL, credential, http.DefaultClient)
+}
+
+// NewWithClient returns a KeyVault instance sending requests with the given HTTP client.
+func NewWithClient(vaultURL string, credential TokenCredential, client *http.Client) *KeyVault {
+	return &KeyVault{
+		vaultURL:   strings.TrimSuffix(vaultURL, "/"),
+		credential: credential,
+		client:     client,
+		ctx:        context.Background(),
+	}
+}
+
+// WithContext returns a copy of the KeyVault which passes the given context to every request.
+func (k *KeyVault) WithContext(ctx context.Context) *KeyVault {
+	return &KeyVault{
+		vaultURL:   k.vaultURL,
+		credential: k.credential,
+		client:     k.client,
+		ctx:        ctx,
+	}
+}
+
+// Get retrieves the object for the given identifier, "[kind/]name[/version]", where kind is one of
+// "secrets" (the default), "keys" or "certificates".
+//
+// Secrets are parsed as a JSON object, falling back to a single value map. RSA keys are returned
+// as a base64 encoded PEM "public_key", suitable for envsecret.PublicKey. Certificates are returned
+// as base64 encoded PEM "certificate" and "private_key" values, suitable for envsecret.Certificate
+// and envsecret.PrivateKey, provided the certificate's private key is exportable.
+func (k *KeyVault) Get(id string) (map[string]interface{}, error) {
+	kind, name := "secrets", id
+	if i := strings.Index(id, "/"); i >= 0 {
+		switch id[:i] {
+		case "secrets", "keys", "certificates":
+			kind, name = id[:i], id[i+1:]
+		}
+	}
+
+	switch kind {
+	case "keys":
+		return k.getKey(name)
+	case "certificates":
+		return k.getCertificate(name)
+	}
+
+	var body struct {
+		Value       string `json:"value"`
+		ContentType string `json:"contentType"`
+	}
+	if err := k.get("secrets/"+name, &body); err != nil {
+		return nil, err
+	}
+
+	switch body.ContentType {
+	case contentTypePEM, contentTypePKCS12:
+		return certificate(body.Value, body.ContentType)
+	}
+
+	return secretvalue.Parse([]byte(body.Value)), nil
+}
+
+// getKey retrieves the public part of an RSA key.
+func (k *KeyVault) getKey(name string) (map[string]interface{}, error) {
+	var body struct {
+		Key struct {
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"key"`
+	}
+	if err := k.get("keys/"+name, &body); err != nil {
+		return nil, err
+	}
+
+	if body.Key.Kty != "RSA" && body.Key.Kty != "RSA-HSM" {
+		return nil, fmt.Errorf("unsupported key type %q", body.Key.Kty)
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(body.Key.N, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(body.Key.E, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key exponent: %w", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"public_key": encode(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	}, nil
+}
+
+// getCertificate retrieves the secret backing a certificate, which holds its private key.
+func (k *KeyVault) getCertificate(name string) (map[string]interface{}, error) {
+	var body struct {
+		Value       string `json:"value"`
+		ContentType string `json:"contentType"`
+	}
+	if err := k.get("secrets/"+name, &body); err != nil {
+		return nil, err
+	}
+
+	return certificate(body.Value, body.ContentType)
+}
+
+// get sends an authenticated request for the object at path and decodes the response into v.
+func (k *KeyVault) get(path string, v interface{}) error {
+	token, err := k.credential.Token(k.ctx)
+	if err != nil {
+		return fmt.Errorf("getting token: %w", err)
+	}
+
+	u := k.vaultURL + "/" + path + "?" + url.Values{"api-version": {apiVersion}}.Encode()
+	req, err := http.NewRequestWithContext(k.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(path, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// certificate converts the value of a secret backing a certificate into a map of base64 encoded
+// PEM "certificate" and "private_key" values.
+func certificate(value, contentType string) (map[string]interface{}, error) {
+	var certs, keys []*pem.Block
+
+	switch contentType {
+	case contentTypePEM:
+		rest := []byte(value)
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			if block.Type == "CERTIFICATE" {
+				certs = append(certs, block)
+			} else if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				keys = append(keys, block)
+			}
+		}
+
+	case contentTypePKCS12:
+		pfx, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding pkcs12: %w", err)
+		}
+
+		key, cert, chain, err := pkcs12.DecodeChain(pfx, "")
+		if err != nil {
+			return nil, fmt.Errorf("decoding pkcs12: %w", err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+		for _, c := range append([]*x509.Certificate{cert}, chain...) {
+			certs = append(certs, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported certificate content type %q", contentType)
+	}
+
+	if len(certs) == 0 || len(keys) != 1 {
+		return nil, errors.New("certificate secret must hold a certificate and exactly one private key")
+	}
+
+	return map[string]interface{}{
+		"certificate": encode(certs...),
+		"private_key": encode(keys...),
+	}, nil
+}
+
+// encode the PEM blocks and base64 encode the result.
+func encode(blocks ...*pem.Block) string {
+	var b []byte
+	for _, block := range blocks {
+		b = append(b, pem.EncodeToMemory(block)...)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// statusError builds an error from an unsuccessful response, translating 404 into envsecret.ErrNotFound.
+func statusError(path string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, path)
+	}
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(b, &body) == nil && body.Error.Message != "" {
+		return fmt.Errorf("getting %s: %s: %s", path, resp.Status, body.Error.Message)
+	}
+
+	return fmt.Errorf("getting %s: %s", path, resp.Status)
+}
@@ -0,0 +1,189 @@
+package azurekeyvault_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/azurekeyvault"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestKeyVault_Get(t *testing.T) {
+	key, cert := generate(t)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pfx, err := pkcs12.Modern.Encode(key, cert, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := map[string]interface{}{
+		"/secrets/db-creds/": map[string]string{"value": "{\"username\":\"user\",\"password\":\"pass\"}"},
+		"/secrets/api-key/":  map[string]string{"value": "abc123"},
+		"/secrets/api-key/1": map[string]string{"value": "old123"},
+		"/secrets/tls-pem/":  map[string]string{"value": string(keyPEM) + string(certPEM), "contentType": "application/x-pem-file"},
+		"/secrets/tls-pfx/":  map[string]string{"value": base64.StdEncoding.EncodeToString(pfx), "contentType": "application/x-pkcs12"},
+		"/keys/signing/": map[string]interface{}{"key": map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+		"/keys/ec/": map[string]interface{}{"key": map[string]string{"kty": "EC"}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":"Unauthorized","message":"invalid token"}}`))
+			return
+		}
+
+		path := r.URL.Path
+		if strings.Count(path, "/") == 2 {
+			path += "/"
+		}
+		object, found := objects[path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"SecretNotFound","message":"not found"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(object)
+	}))
+	defer server.Close()
+
+	subject := secretstore.NewWithClient(server.URL, secretstore.StaticToken("token"), server.Client())
+
+	t.Run("secrets", func(t *testing.T) {
+		actual, err := subject.Get("db-creds")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"username": "user", "password": "pass"}, actual)
+
+		actual, err = subject.Get("secrets/api-key")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"value": "abc123"}, actual)
+
+		actual, err = subject.Get("api-key/1")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"value": "old123"}, actual)
+
+		_, err = subject.Get("missing")
+		assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		actual, err := subject.Get("keys/signing")
+		assert.NoError(t, err)
+
+		publicKey := envsecret.NewPublicKey("keys/signing")
+		assert.NoError(t, publicKey.Decode(actual))
+		assert.Equal(t, &key.PublicKey, publicKey.Key)
+
+		_, err = subject.Get("keys/ec")
+		assert.EqualError(t, err, "unsupported key type \"EC\"")
+	})
+
+	for _, id := range []string{"certificates/tls-pem", "certificates/tls-pfx", "tls-pem"} {
+		t.Run(id, func(t *testing.T) {
+			actual, err := subject.Get(id)
+			assert.NoError(t, err)
+
+			certificate := envsecret.NewCertificate(id)
+			assert.NoError(t, certificate.Decode(actual))
+			assert.Equal(t, cert.Raw, certificate.Certificate.Certificate[0])
+
+			privateKey := envsecret.NewPrivateKey(id)
+			assert.NoError(t, privateKey.Decode(actual))
+			assert.True(t, key.Equal(privateKey.Key))
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		subject := secretstore.NewWithClient(server.URL, secretstore.StaticToken("wrong"), server.Client())
+
+		_, err := subject.Get("api-key")
+		assert.EqualError(t, err, "getting secrets/api-key: 401 Unauthorized: invalid token")
+	})
+
+	t.Run("credential error", func(t *testing.T) {
+		subject := secretstore.NewWithClient(server.URL, failingCredential{}, server.Client())
+
+		_, err := subject.Get("api-key")
+		assert.EqualError(t, err, "getting token: no credentials")
+	})
+}
+
+func TestKeyVault_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, found := fixtures[strings.Trim(strings.TrimPrefix(r.URL.Path, "/secrets/"), "/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"code":"SecretNotFound","message":"not found"}}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"value": string(storetest.Encode(secret))})
+		}))
+		t.Cleanup(server.Close)
+
+		return secretstore.NewWithClient(server.URL, secretstore.StaticToken("token"), server.Client())
+	}, storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.KeyVault).WithContext(ctx)
+	}))
+}
+
+type failingCredential struct{}
+
+func (failingCredential) Token(context.Context) (string, error) {
+	return "", errors.New("no credentials")
+}
+
+// generate an RSA key and a self-signed certificate for it.
+func generate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "envsecret.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, cert
+}
@@ -0,0 +1,443 @@
+package bitwarden
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+const (
+	// DefaultAPIURL is the API of Bitwarden's US cloud.
+	DefaultAPIURL = "https://api.bitwarden.com"
+	// DefaultIdentityURL is the identity service of Bitwarden's US cloud.
+	DefaultIdentityURL = "https://identity.bitwarden.com"
+)
+
+var (
+	// ErrInvalidAccessToken is returned by New for access tokens not of the form
+	// "0.<client id>.<client secret>:<encryption key>".
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	// ErrDecrypt is returned when an encrypted value cannot be authenticated or decrypted.
+	ErrDecrypt = errors.New("decrypting value")
+
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Config for a SecretsManager store.
+type Config struct {
+	// AccessToken of a machine account, as generated by the Bitwarden web vault.
+	AccessToken string
+	// APIURL defaults to DefaultAPIURL. For self-hosted servers it is usually https://<host>/api.
+	APIURL string
+	// IdentityURL defaults to DefaultIdentityURL. For self-hosted servers it is usually
+	// https://<host>/identity.
+	IdentityURL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// SecretsManager reads secrets from Bitwarden Secrets Manager.
+//
+// Secret keys, values and project names are encrypted by Bitwarden with the organization's key,
+// which is itself encrypted with a key held in the access token, so everything is decrypted
+// locally and never leaves the process in plain text.
+type SecretsManager struct {
+	apiURL      string
+	identityURL string
+	client      *http.Client
+	token       accessToken
+	session     *session
+	ctx         context.Context
+}
+
+// New returns a SecretsManager instance for the given configuration.
+func New(config Config) (*SecretsManager, error) {
+	token, err := parseAccessToken(config.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SecretsManager{
+		apiURL:      strings.TrimSuffix(config.APIURL, "/"),
+		identityURL: strings.TrimSuffix(config.IdentityURL, "/"),
+		client:      config.Client,
+		token:       token,
+		session:     &session{},
+		ctx:         context.Background(),
+	}
+	if s.apiURL == "" {
+		s.apiURL = DefaultAPIURL
+	}
+	if s.identityURL == "" {
+		s.identityURL = DefaultIdentityURL
+	}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+
+	return s, nil
+}
+
+// WithContext returns a copy of the SecretsManager store which passes the given context to every
+// request. The copy shares the original's login session.
+func (s *SecretsManager) WithContext(ctx context.Context) *SecretsManager {
+	c := *s
+	c.ctx = ctx
+	return &c
+}
+
+// Get retrieves the secret for the given identifier, either "id[/field]" where id is the
+// secret's UUID, or "project/key[/field]" where project is a project name and key the secret's
+// key. A JSON object value is returned as the secret map, so a value such as
+// {"username": "u", "password": "p"} decodes directly into envsecret.Login, and any other value
+// is returned as a single value. If a field is given, only that entry of a JSON object value is
+// returned.
+func (s *SecretsManager) Get(id string) (map[string]interface{}, error) {
+	sess, err := s.login()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(id, "/", 3)
+	secretID, field := parts[0], parts[1:]
+	if !uuidPattern.MatchString(secretID) {
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid identifier %q, expected id[/field] or project/key[/field]", id)
+		}
+		if secretID, err = s.lookup(sess, parts[0], parts[1], id); err != nil {
+			return nil, err
+		}
+		field = parts[2:]
+	}
+
+	var secret struct {
+		Value string `json:"value"`
+	}
+	if err := s.get(sess, "secrets/"+url.PathEscape(secretID), &secret); err != nil {
+		return nil, err
+	}
+
+	value, err := decrypt(secret.Value, sess.key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+
+	m := secretvalue.Parse(value)
+	if len(field) == 0 {
+		return m, nil
+	}
+
+	if _, ok := m[secretvalue.Key]; ok && len(m) == 1 {
+		return nil, fmt.Errorf("%w: %s: value is not a JSON object", envsecret.ErrNotFound, id)
+	}
+	v, ok := m[field[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	return map[string]interface{}{
+		secretvalue.Key: v,
+	}, nil
+}
+
+// lookup the ID of the secret with the given key in the project with the given name.
+func (s *SecretsManager) lookup(sess *session, project, key, id string) (string, error) {
+	var projects struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := s.get(sess, "organizations/"+sess.organization+"/projects", &projects); err != nil {
+		return "", err
+	}
+
+	var projectIDs []string
+	for _, p := range projects.Data {
+		name, err := decrypt(p.Name, sess.key)
+		if err != nil {
+			return "", fmt.Errorf("project %s: %w", p.ID, err)
+		}
+		if string(name) == project {
+			projectIDs = append(projectIDs, p.ID)
+		}
+	}
+	if len(projectIDs) != 1 {
+		return "", matchError(project, id, len(projectIDs))
+	}
+
+	var secrets struct {
+		Secrets []struct {
+			ID  string `json:"id"`
+			Key string `json:"key"`
+		} `json:"secrets"`
+	}
+	if err := s.get(sess, "projects/"+projectIDs[0]+"/secrets", &secrets); err != nil {
+		return "", err
+	}
+
+	var secretIDs []string
+	for _, secret := range secrets.Secrets {
+		k, err := decrypt(secret.Key, sess.key)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", secret.ID, err)
+		}
+		if string(k) == key {
+			secretIDs = append(secretIDs, secret.ID)
+		}
+	}
+	if len(secretIDs) != 1 {
+		return "", matchError(key, id, len(secretIDs))
+	}
+
+	return secretIDs[0], nil
+}
+
+// matchError reports a name matching no or several objects.
+func matchError(name, id string, n int) error {
+	if n == 0 {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return fmt.Errorf("%q matches %d objects, use an ID", name, n)
+}
+
+// get sends an authenticated API request for the resource at path and decodes the response into v.
+func (s *SecretsManager) get(sess *session, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.apiURL+"/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+sess.accessToken)
+
+	return s.do(req, path, v)
+}
+
+// do sends the request and decodes a successful JSON response into v.
+func (s *SecretsManager) do(req *http.Request, path string, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		if msg := strings.TrimSpace(string(b)); msg != "" {
+			return fmt.Errorf("%s: %s: %s", path, resp.Status, msg)
+		}
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// session holds the state of a login with the access token, shared by copies of a SecretsManager.
+type session struct {
+	mu           sync.Mutex
+	accessToken  string
+	expires      time.Time
+	organization string
+	key          key
+}
+
+// login with the access token, reusing the current session until shortly before it expires.
+func (s *SecretsManager) login() (*session, error) {
+	s.session.mu.Lock()
+	defer s.session.mu.Unlock()
+
+	if s.session.accessToken != "" && time.Now().Before(s.session.expires) {
+		return s.session, nil
+	}
+
+	form := url.Values{
+		"scope":         {"api.secrets"},
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.token.clientID},
+		"client_secret": {s.token.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.identityURL+"/connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		EncryptedPayload string `json:"encrypted_payload"`
+	}
+	if err := s.do(req, "logging in", &resp); err != nil {
+		return nil, err
+	}
+
+	payload, err := decrypt(resp.EncryptedPayload, s.token.key)
+	if err != nil {
+		return nil, fmt.Errorf("logging in: %w", err)
+	}
+	var p struct {
+		EncryptionKey string `json:"encryptionKey"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("logging in: decoding payload: %w", err)
+	}
+	orgKey, err := base64.StdEncoding.DecodeString(p.EncryptionKey)
+	if err != nil || len(orgKey) != 64 {
+		return nil, fmt.Errorf("logging in: %w: invalid organization key", ErrDecrypt)
+	}
+
+	organization, err := organizationClaim(resp.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("logging in: %w", err)
+	}
+
+	s.session.accessToken = resp.AccessToken
+	s.session.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	s.session.organization = organization
+	s.session.key = key{enc: orgKey[:32], mac: orgKey[32:]}
+
+	return s.session, nil
+}
+
+// organizationClaim reads the organization ID from the claims of a JWT access token.
+func organizationClaim(jwt string) (string, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed access token")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decoding access token: %w", err)
+	}
+
+	var claims struct {
+		Organization string `json:"organization"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return "", fmt.Errorf("decoding access token: %w", err)
+	}
+	if claims.Organization == "" {
+		return "", errors.New("access token has no organization")
+	}
+
+	return claims.Organization, nil
+}
+
+// accessToken is a parsed machine account access token.
+type accessToken struct {
+	clientID     string
+	clientSecret string
+	key          key
+}
+
+// parseAccessToken parses a token of the form "0.<client id>.<client secret>:<encryption key>"
+// and derives the key its login payload is encrypted with.
+func parseAccessToken(s string) (accessToken, error) {
+	credentials, encoded, ok := strings.Cut(s, ":")
+	if !ok {
+		return accessToken{}, ErrInvalidAccessToken
+	}
+
+	parts := strings.Split(credentials, ".")
+	if len(parts) != 3 || parts[0] != "0" || parts[1] == "" || parts[2] == "" {
+		return accessToken{}, ErrInvalidAccessToken
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != 16 {
+		return accessToken{}, ErrInvalidAccessToken
+	}
+
+	mac := hmac.New(sha256.New, []byte("bitwarden-accesstoken"))
+	mac.Write(secret)
+	derived := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, mac.Sum(nil), []byte("sm-access-token")), derived); err != nil {
+		return accessToken{}, err
+	}
+
+	return accessToken{
+		clientID:     parts[1],
+		clientSecret: parts[2],
+		key:          key{enc: derived[:32], mac: derived[32:]},
+	}, nil
+}
+
+// key is a symmetric AES-256 key and its HMAC-SHA256 key.
+type key struct {
+	enc []byte
+	mac []byte
+}
+
+// decrypt a Bitwarden encrypted string of type 2, "2.<iv>|<ciphertext>|<mac>", which is
+// AES-256-CBC encrypted and authenticated with HMAC-SHA256 over the IV and ciphertext.
+func decrypt(s string, k key) ([]byte, error) {
+	encType, data, ok := strings.Cut(s, ".")
+	if !ok || encType != "2" {
+		return nil, fmt.Errorf("%w: unsupported encryption type", ErrDecrypt)
+	}
+
+	parts := strings.Split(data, "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed value", ErrDecrypt)
+	}
+	var decoded [3][]byte
+	for i, p := range parts {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+		}
+		decoded[i] = b
+	}
+	iv, ciphertext, sum := decoded[0], decoded[1], decoded[2]
+
+	mac := hmac.New(sha256.New, k.mac)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, fmt.Errorf("%w: MAC mismatch", ErrDecrypt)
+	}
+
+	block, err := aes.NewCipher(k.enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: malformed value", ErrDecrypt)
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	n := int(plaintext[len(plaintext)-1])
+	if n == 0 || n > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, fmt.Errorf("%w: invalid padding", ErrDecrypt)
+	}
+
+	return plaintext[:len(plaintext)-n], nil
+}
@@ -0,0 +1,360 @@
+package bitwarden_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/bitwarden"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+const (
+	organizationID = "5e1d3d4a-0c8d-4b8e-9f3c-7d1a2b3c4d5e"
+	clientID       = "ec2c1d46-6a4b-4751-a310-af9601317f2d"
+	clientSecret   = "C2IgxjjLF7qSshsbwe8JGcbM075YXw"
+	secretID       = "a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"
+
+	// accessToken is the access token from the can_decode_access_token test of the Bitwarden
+	// SDK, which derives from it the key
+	// H9/oIRLtL9nGCQOVDjSMoEbJsjWXSOCb3qeyDt6ckzS3FhyboEDWyTP/CQfbIszNmAVg2ExFganG1FVFGXO/Jg==.
+	accessToken = "0." + clientID + "." + clientSecret + ":X8vbvA0bduihIDe/qrzIQQ=="
+
+	// organizationKey is the organization's encryption key, and payload the login payload
+	// {"encryptionKey":"<organizationKey>"}, encrypted independently of this package with
+	// OpenSSL under the halves of the key derived from accessToken:
+	//
+	//	openssl enc -aes-256-cbc -in payload.json -out ciphertext \
+	//		-K 1fdfe82112ed2fd9c60903950e348ca046c9b2359748e09bdea7b20ede9c9334 \
+	//		-iv 2fa855f1377a32ff16ffdb2d76b61880
+	//	openssl dgst -sha256 -binary -mac HMAC \
+	//		-macopt hexkey:b7161c9ba040d6c933ff0907db22cccd980560d84c4581a9c6d455451973bf26 iv+ciphertext
+	organizationKey = "aqxJ5psURcO69V2h88z4HFOzlREvpUigYoDWddItOZzrpfPaL30C+fPb3L2VjknZWhw9vL8c4yVAcl8E0DUIrg=="
+	payload         = "2.L6hV8Td6Mv8W/9stdrYYgA==|66e2UCdOf4AiS1CS1cBcUJ8TXIBCiw9Vrcfx3wqJ80gIY3lYZ2nLJawqkHYaKymth" +
+		"INzN29Y5v4Km/eNtWRPTURDEj050smid9W3Y3ZvipB43Asonjp+/V6tk6VYrdJAmpXtqdURYRoIzA4vEeZeUQ==|" +
+		"VQAwPJFkNZ4B8TZzP1uttegiI0btDPE2BKvJ0aX0OpY="
+)
+
+func TestSecretsManager_Get(t *testing.T) {
+	server := newServer(t, vendors)
+	defer server.Close()
+
+	subject, err := secretstore.New(secretstore.Config{
+		AccessToken: server.accessToken,
+		APIURL:      server.URL + "/api",
+		IdentityURL: server.URL + "/identity",
+	})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "by ID",
+			id:       secretID,
+			expected: map[string]interface{}{"username": "vendor", "password": "hunter2"},
+		},
+		{
+			name:     "by ID with field",
+			id:       secretID + "/password",
+			expected: map[string]interface{}{"value": "hunter2"},
+		},
+		{
+			name:     "by project and key",
+			id:       "vendors/portal",
+			expected: map[string]interface{}{"username": "vendor", "password": "hunter2"},
+		},
+		{
+			name:     "by project and key with field",
+			id:       "vendors/portal/username",
+			expected: map[string]interface{}{"value": "vendor"},
+		},
+		{
+			name:     "plain value",
+			id:       "vendors/api-key",
+			expected: map[string]interface{}{"value": "abc123"},
+		},
+		{
+			name: "field of plain value",
+			id:   "vendors/api-key/value",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown field",
+			id:   "vendors/portal/other",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown key",
+			id:   "vendors/other",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown project",
+			id:   "other/portal",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown ID",
+			id:   "00000000-0000-4000-8000-000000000000",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "tampered value",
+			id:   "vendors/tampered",
+			err:  secretstore.ErrDecrypt,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := subject.Get(c.id)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err), "expected %v, got %v", c.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+
+	assert.Equal(t, int32(1), server.logins.Load())
+}
+
+func TestSecretsManager_Get_Login(t *testing.T) {
+	server := newServer(t, vendors)
+	defer server.Close()
+
+	subject, err := secretstore.New(secretstore.Config{
+		AccessToken: server.accessToken,
+		APIURL:      server.URL + "/api",
+		IdentityURL: server.URL + "/identity",
+	})
+	assert.NoError(t, err)
+
+	spec := struct {
+		Vendor envsecret.Login
+	}{
+		Vendor: envsecret.NewLogin("vendors/portal"),
+	}
+
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, "vendor", spec.Vendor.Username)
+	assert.Equal(t, "hunter2", spec.Vendor.Password)
+}
+
+func TestSecretsManager_Errors(t *testing.T) {
+	server := newServer(t, vendors)
+	defer server.Close()
+
+	for _, token := range []string{
+		"",
+		"0." + clientID + "." + clientSecret,
+		"1." + clientID + "." + clientSecret + ":" + base64.StdEncoding.EncodeToString(make([]byte, 16)),
+		"0." + clientID + "." + clientSecret + ":" + base64.StdEncoding.EncodeToString(make([]byte, 8)),
+	} {
+		_, err := secretstore.New(secretstore.Config{AccessToken: token})
+		assert.True(t, errors.Is(err, secretstore.ErrInvalidAccessToken), "token %q", token)
+	}
+
+	wrongSecret, err := secretstore.New(secretstore.Config{
+		AccessToken: strings.Replace(server.accessToken, clientSecret, "wrong", 1),
+		APIURL:      server.URL + "/api",
+		IdentityURL: server.URL + "/identity",
+	})
+	assert.NoError(t, err)
+	_, err = wrongSecret.Get(secretID)
+	assert.EqualError(t, err, `logging in: 400 Bad Request: {"error":"invalid_client"}`)
+
+	wrongKey, err := secretstore.New(secretstore.Config{
+		AccessToken: clientToken(make([]byte, 16)),
+		APIURL:      server.URL + "/api",
+		IdentityURL: server.URL + "/identity",
+	})
+	assert.NoError(t, err)
+	_, err = wrongKey.Get(secretID)
+	assert.True(t, errors.Is(err, secretstore.ErrDecrypt))
+
+	subject, err := secretstore.New(secretstore.Config{
+		AccessToken: server.accessToken,
+		APIURL:      server.URL + "/api",
+		IdentityURL: server.URL + "/identity",
+	})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = subject.WithContext(ctx).Get(secretID)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestSecretsManager_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		secrets := make(map[string][2]string, len(fixtures))
+		for id, secret := range fixtures {
+			key := strings.TrimPrefix(id, "vendors/")
+			secrets["id-"+key] = [2]string{key, string(storetest.Encode(secret))}
+		}
+		server := newServer(t, secrets)
+		t.Cleanup(server.Close)
+
+		subject, err := secretstore.New(secretstore.Config{
+			AccessToken: server.accessToken,
+			APIURL:      server.URL + "/api",
+			IdentityURL: server.URL + "/identity",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return subject
+	}, storetest.IDs(func(name string) string {
+		return "vendors/" + name
+	}), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.SecretsManager).WithContext(ctx)
+	}))
+}
+
+// server is a stand-in for the Bitwarden identity and Secrets Manager APIs, holding one project.
+type server struct {
+	*httptest.Server
+	accessToken string
+	logins      atomic.Int32
+}
+
+// vendors holds the secrets of the "vendors" project, keyed by ID, as a key and plaintext value.
+var vendors = map[string][2]string{
+	secretID:                               {"portal", `{"username": "vendor", "password": "hunter2"}`},
+	"b2c3d4e5-f6a7-4b8c-9d0e-1f2a3b4c5d6e": {"api-key", "abc123"},
+	"c3d4e5f6-a7b8-4c9d-8e1f-2a3b4c5d6e7f": {"tampered", ""},
+}
+
+func newServer(t *testing.T, secrets map[string][2]string) *server {
+	t.Helper()
+
+	orgKey, err := base64.StdEncoding.DecodeString(organizationKey)
+	assert.NoError(t, err)
+
+	encrypt := func(k []byte, plaintext string) string {
+		return encryptString(t, k[:32], k[32:], plaintext)
+	}
+	jwtClaims, _ := json.Marshal(map[string]string{"organization": organizationID, "client_id": clientID})
+	jwt := "e30." + base64.RawURLEncoding.EncodeToString(jwtClaims) + ".c2ln"
+
+	tampered := encrypt(orgKey, "tampered")
+	tampered = tampered[:len(tampered)-4] + "AAA="
+
+	s := &server{accessToken: accessToken}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /identity/connect/token", func(w http.ResponseWriter, r *http.Request) {
+		s.logins.Add(1)
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "api.secrets" ||
+			r.FormValue("client_id") != clientID || r.FormValue("client_secret") != clientSecret {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"invalid_client"}`)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":      jwt,
+			"expires_in":        3600,
+			"token_type":        "Bearer",
+			"encrypted_payload": payload,
+		})
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+jwt {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/organizations/"+organizationID+"/projects", authorized(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]string{
+			{"id": "p1", "organizationId": organizationID, "name": encrypt(orgKey, "vendors")},
+			{"id": "p2", "organizationId": organizationID, "name": encrypt(orgKey, "internal")},
+		}})
+	}))
+	mux.HandleFunc("GET /api/projects/p1/secrets", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var list []map[string]string
+		for id, secret := range secrets {
+			list = append(list, map[string]string{"id": id, "organizationId": organizationID, "key": encrypt(orgKey, secret[0])})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"secrets": list})
+	}))
+	mux.HandleFunc("GET /api/projects/p2/secrets", authorized(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"secrets":[]}`)
+	}))
+	mux.HandleFunc("GET /api/secrets/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := secrets[r.PathValue("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		value := encrypt(orgKey, secret[1])
+		if secret[0] == "tampered" {
+			value = tampered
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id":    r.PathValue("id"),
+			"key":   encrypt(orgKey, secret[0]),
+			"value": value,
+		})
+	}))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// clientToken returns an access token for the test client with the given encryption key.
+func clientToken(key []byte) string {
+	return "0." + clientID + "." + clientSecret + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+// encryptString produces a type 2 encrypted string of the plaintext.
+func encryptString(t *testing.T, encKey, macKey []byte, plaintext string) string {
+	t.Helper()
+
+	block, err := aes.NewCipher(encKey)
+	assert.NoError(t, err)
+
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append([]byte(plaintext), []byte(strings.Repeat(string(rune(n)), n))...)
+	iv := random(t, aes.BlockSize)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+
+	return "2." + base64.StdEncoding.EncodeToString(iv) + "|" +
+		base64.StdEncoding.EncodeToString(ciphertext) + "|" +
+		base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func random(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	assert.NoError(t, err)
+	return b
+}
@@ -0,0 +1,128 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// waitTime bounds each blocking query made by Watch.
+const waitTime = 5 * time.Minute
+
+// Consul reads secrets from the Consul KV store.
+type Consul struct {
+	client *api.Client
+	ctx    context.Context
+}
+
+// New returns a Consul instance configured to use the given client.
+func New(client *api.Client) *Consul {
+	return &Consul{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the Consul store which passes the given context to every request.
+func (c *Consul) WithContext(ctx context.Context) *Consul {
+	return &Consul{
+		client: c.client,
+		ctx:    ctx,
+	}
+}
+
+// Get retrieves the given key, parsing its value as a JSON object and falling back to a single
+// value map. An identifier ending in "/" is treated as a prefix, and every key beneath it is
+// returned keyed by its name relative to the prefix.
+func (c *Consul) Get(id string) (map[string]interface{}, error) {
+	m, _, err := c.get(id, (&api.QueryOptions{}).WithContext(c.ctx))
+	return m, err
+}
+
+// Watch calls fn with the current value of the key or prefix for the given identifier, and again
+// whenever it changes, using blocking queries until the context is done. Should the key or prefix
+// be deleted, fn is called with envsecret.ErrNotFound.
+func (c *Consul) Watch(ctx context.Context, id string, fn func(map[string]interface{}, error)) error {
+	var (
+		index uint64
+		last  interface{}
+	)
+	for {
+		m, meta, err := c.get(id, (&api.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  waitTime,
+		}).WithContext(ctx))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if meta == nil {
+			fn(nil, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		// The index may go backwards, e.g. after a snapshot restore, in which case it is reset.
+		if meta.LastIndex < index {
+			index = 0
+			continue
+		}
+		if meta.LastIndex == index {
+			continue
+		}
+		index = meta.LastIndex
+
+		// Blocking queries may return without the watched value changing, so only changes are reported.
+		var current interface{} = m
+		if err != nil {
+			current = err.Error()
+		}
+		if !reflect.DeepEqual(current, last) {
+			last = current
+			fn(m, err)
+		}
+	}
+}
+
+// get the key or prefix, returning the query metadata for any response from Consul.
+func (c *Consul) get(id string, opts *api.QueryOptions) (map[string]interface{}, *api.QueryMeta, error) {
+	kv := c.client.KV()
+
+	if strings.HasSuffix(id, "/") {
+		pairs, meta, err := kv.List(id, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m := make(map[string]interface{}, len(pairs))
+		for _, pair := range pairs {
+			if key := strings.TrimPrefix(pair.Key, id); key != "" && !strings.HasSuffix(key, "/") {
+				m[key] = string(pair.Value)
+			}
+		}
+		if len(m) == 0 {
+			return nil, meta, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+		}
+
+		return m, meta, nil
+	}
+
+	pair, meta, err := kv.Get(id, opts)
+	if err != nil {
+		return nil, nil, err
+	} else if pair == nil {
+		return nil, meta, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	return secretvalue.Parse(pair.Value), meta, nil
+}
@@ -0,0 +1,224 @@
+package consul_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/consul"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestConsul_Get(t *testing.T) {
+	kv := newFakeKV(map[string]string{
+		"app/json":        "{\"key\":\"value\"}",
+		"app/plain":       "plain value",
+		"app/db/username": "user",
+		"app/db/password": "pass",
+		"app/db/nested/":  "",
+	})
+	server := httptest.NewServer(kv)
+	defer server.Close()
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "json value",
+			id:       "app/json",
+			expected: map[string]interface{}{"key": "value"},
+		},
+		{
+			name:     "plain value",
+			id:       "app/plain",
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "prefix",
+			id:       "app/db/",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name: "missing key",
+			id:   "app/missing",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "missing prefix",
+			id:   "missing/",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client(t, server))
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestConsul_Watch(t *testing.T) {
+	kv := newFakeKV(map[string]string{
+		"app/db/username": "user",
+		"app/db/password": "old",
+	})
+	server := httptest.NewServer(kv)
+	defer server.Close()
+
+	subject := secretstore.New(client(t, server))
+
+	type update struct {
+		value map[string]interface{}
+		err   error
+	}
+	updates := make(chan update, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "app/db/", func(m map[string]interface{}, err error) {
+			updates <- update{m, err}
+		})
+	}()
+
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return update{}
+		}
+	}
+
+	assert.Equal(t, update{value: map[string]interface{}{"username": "user", "password": "old"}}, next())
+
+	kv.Put("other/key", "unrelated")
+	kv.Put("app/db/password", "new")
+	assert.Equal(t, update{value: map[string]interface{}{"username": "user", "password": "new"}}, next())
+
+	kv.Delete("app/db/username", "app/db/password")
+	u := next()
+	assert.Nil(t, u.value)
+	assert.True(t, errors.Is(u.err, envsecret.ErrNotFound))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, updates)
+}
+
+func client(t *testing.T, server *httptest.Server) *api.Client {
+	t.Helper()
+	c, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// fakeKV is an in-memory stand-in for the Consul KV HTTP API supporting blocking queries.
+type fakeKV struct {
+	mu      sync.Mutex
+	values  map[string]string
+	index   uint64
+	changed chan struct{}
+}
+
+func newFakeKV(values map[string]string) *fakeKV {
+	return &fakeKV{values: values, index: 1, changed: make(chan struct{})}
+}
+
+func (f *fakeKV) Put(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = value
+	f.notify()
+}
+
+func (f *fakeKV) Delete(keys ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.values, key)
+	}
+	f.notify()
+}
+
+func (f *fakeKV) notify() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	query := r.URL.Query()
+
+	f.mu.Lock()
+	if wait, _ := strconv.ParseUint(query.Get("index"), 10, 64); wait > 0 && wait >= f.index {
+		changed := f.changed
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Second):
+		}
+		f.mu.Lock()
+	}
+	defer f.mu.Unlock()
+
+	var pairs []*api.KVPair
+	for k, v := range f.values {
+		if k == key || (query.Has("recurse") && strings.HasPrefix(k, key)) {
+			pairs = append(pairs, &api.KVPair{Key: k, Value: []byte(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestConsul_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		values := make(map[string]string, len(fixtures))
+		for id, secret := range fixtures {
+			values[id] = string(storetest.Encode(secret))
+		}
+		server := httptest.NewServer(newFakeKV(values))
+		t.Cleanup(server.Close)
+		return secretstore.New(client(t, server))
+	}, storetest.IDs(func(name string) string {
+		return "app/" + name
+	}), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.Consul).WithContext(ctx)
+	}))
+}
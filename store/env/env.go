@@ -0,0 +1,46 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// fileSuffix marks a variable holding the path of a file containing the secret, following the
+// convention used by Docker images, e.g. POSTGRES_PASSWORD_FILE.
+const fileSuffix = "_FILE"
+
+// EnvStore reads secrets from environment variables, for local development and
+// environments where secrets are injected directly.
+type EnvStore struct{}
+
+// New returns a new EnvStore.
+func New() *EnvStore {
+	return &EnvStore{}
+}
+
+// Get returns the value of the environment variable named by the identifier. If that variable
+// is unset, the file named by the variable with a "_FILE" suffix is read instead. The value is
+// parsed as a JSON object, falling back to a single value map.
+func (*EnvStore) Get(name string) (map[string]interface{}, error) {
+	value, found := os.LookupEnv(name)
+	path, foundFile := os.LookupEnv(name + fileSuffix)
+
+	switch {
+	case found && foundFile:
+		return nil, fmt.Errorf("both %s and %s%s are set", name, name, fileSuffix)
+	case found:
+		return secretvalue.Parse([]byte(value)), nil
+	case foundFile:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s%s: %w", name, fileSuffix, err)
+		}
+		return secretvalue.Parse([]byte(strings.TrimSuffix(string(b), "\n"))), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, name)
+}
@@ -0,0 +1,92 @@
+package env_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/env"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestEnv_Get(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("{\"username\":\"user\",\"password\":\"pass\"}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ENVSECRET_TEST_STRING", "value")
+	t.Setenv("ENVSECRET_TEST_MAP", "{\"key\":\"value\"}")
+	t.Setenv("ENVSECRET_TEST_LOGIN_FILE", path)
+	t.Setenv("ENVSECRET_TEST_MISSING_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("ENVSECRET_TEST_BOTH", "value")
+	t.Setenv("ENVSECRET_TEST_BOTH_FILE", path)
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "scalar value",
+			id:       "ENVSECRET_TEST_STRING",
+			expected: map[string]interface{}{"value": "value"},
+		},
+		{
+			name:     "json value",
+			id:       "ENVSECRET_TEST_MAP",
+			expected: map[string]interface{}{"key": "value"},
+		},
+		{
+			name:     "file variable",
+			id:       "ENVSECRET_TEST_LOGIN",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name: "unset",
+			id:   "ENVSECRET_TEST_UNSET",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "missing file",
+			id:   "ENVSECRET_TEST_MISSING",
+			err:  os.ErrNotExist,
+		},
+		{
+			name: "both set",
+			id:   "ENVSECRET_TEST_BOTH",
+			err:  errors.New("both ENVSECRET_TEST_BOTH and ENVSECRET_TEST_BOTH_FILE are set"),
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New()
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err) || err.Error() == test.err.Error(), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestEnv_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		for id, secret := range fixtures {
+			t.Setenv(id, string(storetest.Encode(secret)))
+		}
+		return secretstore.New()
+	}, storetest.IDs(func(name string) string {
+		return "ENVSECRET_CONFORMANCE_" + strings.ToUpper(name)
+	}))
+}
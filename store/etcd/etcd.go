@@ -0,0 +1,173 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// Etcd reads secrets from etcd.
+type Etcd struct {
+	client etcdKV
+	ctx    context.Context
+}
+
+// New returns an Etcd instance configured to use the given client, typically a *clientv3.Client.
+func New(client etcdKV) *Etcd {
+	return &Etcd{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the Etcd store which passes the given context to every request.
+func (e *Etcd) WithContext(ctx context.Context) *Etcd {
+	return &Etcd{
+		client: e.client,
+		ctx:    ctx,
+	}
+}
+
+// Get retrieves the given key, parsing its value as a JSON object and falling back to a single
+// value map. An identifier ending in "/" is treated as a prefix, and every key beneath it is
+// returned keyed by its name relative to the prefix.
+func (e *Etcd) Get(id string) (map[string]interface{}, error) {
+	m, _, err := e.get(e.ctx, id, 0)
+	return m, err
+}
+
+// Watch calls fn with the current value of the key or prefix for the given identifier, and again
+// whenever it changes, until the context is done. Should the key or prefix be deleted, fn is
+// called with envsecret.ErrNotFound. Should the watched revision be compacted, the value is read
+// again, delivered and watched from its new revision. Should a read or the watch fail, fn is called
+// with the error and the watch is retried after a backoff.
+func (e *Etcd) Watch(ctx context.Context, id string, fn func(map[string]interface{}, error)) error {
+	ctx = clientv3.WithRequireLeader(ctx)
+
+	var (
+		revision int64
+		backoff  = minBackoff
+	)
+	for {
+		if revision == 0 {
+			m, current, err := e.get(ctx, id, 0)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			fn(m, err)
+			if current == 0 {
+				if backoff, err = wait(ctx, backoff); err != nil {
+					return err
+				}
+				continue
+			}
+			revision = current
+		}
+
+		var received int
+		failed := false
+		for response := range e.client.Watch(ctx, id, options(id, revision+1)...) {
+			received++
+			if response.CompactRevision != 0 {
+				// The revisions since the last read are gone, so read the value again.
+				revision = 0
+				break
+			}
+			if err := response.Err(); err != nil {
+				fn(nil, err)
+				failed = true
+				break
+			}
+			if len(response.Events) == 0 {
+				continue
+			}
+
+			// Read the value as of the latest event, so a prefix is reported as a whole.
+			revision = response.Header.Revision
+			m, _, err := e.get(ctx, id, revision)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fn(m, err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if failed || received == 0 {
+			var err error
+			if backoff, err = wait(ctx, backoff); err != nil {
+				return err
+			}
+		} else {
+			backoff = minBackoff
+		}
+	}
+}
+
+// Backoff bounds between failed or empty watches.
+const (
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// wait for the backoff or until the context is done, returning the next, doubled backoff.
+func wait(ctx context.Context, backoff time.Duration) (time.Duration, error) {
+	select {
+	case <-ctx.Done():
+		return backoff, ctx.Err()
+	case <-time.After(backoff):
+	}
+
+	if backoff *= 2; backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff, nil
+}
+
+// get the key or prefix, at the given revision if non-zero, returning the revision read.
+func (e *Etcd) get(ctx context.Context, id string, revision int64) (map[string]interface{}, int64, error) {
+	response, err := e.client.Get(ctx, id, options(id, revision)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	revision = response.Header.Revision
+
+	if len(response.Kvs) == 0 {
+		return nil, revision, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	if !strings.HasSuffix(id, "/") {
+		return secretvalue.Parse(response.Kvs[0].Value), revision, nil
+	}
+
+	m := make(map[string]interface{}, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		if key := strings.TrimPrefix(string(kv.Key), id); key != "" {
+			m[key] = string(kv.Value)
+		}
+	}
+
+	return m, revision, nil
+}
+
+// options selecting the key or prefix at the given revision.
+func options(id string, revision int64) []clientv3.OpOption {
+	opts := []clientv3.OpOption{clientv3.WithRev(revision)}
+	if strings.HasSuffix(id, "/") {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	return opts
+}
+
+type etcdKV interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
@@ -0,0 +1,345 @@
+package etcd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/etcd"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestEtcd_Get(t *testing.T) {
+	kv := newFakeKV()
+	kv.Put(map[string]string{
+		"app/json":        "{\"key\":\"value\"}",
+		"app/plain":       "plain value",
+		"app/db/username": "user",
+		"app/db/password": "pass",
+	})
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "json value",
+			id:       "app/json",
+			expected: map[string]interface{}{"key": "value"},
+		},
+		{
+			name:     "plain value",
+			id:       "app/plain",
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "prefix",
+			id:       "app/db/",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name: "missing key",
+			id:   "app/missing",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "missing prefix",
+			id:   "missing/",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(kv)
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestEtcd_Watch(t *testing.T) {
+	kv := newFakeKV()
+	kv.Put(map[string]string{"app/db/username": "user", "app/db/password": "old"})
+
+	subject := secretstore.New(kv)
+
+	type update struct {
+		value map[string]interface{}
+		err   error
+	}
+	updates := make(chan update, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "app/db/", func(m map[string]interface{}, err error) {
+			updates <- update{m, err}
+		})
+	}()
+
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return update{}
+		}
+	}
+
+	assert.Equal(t, update{value: map[string]interface{}{"username": "user", "password": "old"}}, next())
+
+	kv.Put(map[string]string{"other/key": "unrelated"})
+	kv.Put(map[string]string{"app/db/password": "new"})
+	assert.Equal(t, update{value: map[string]interface{}{"username": "user", "password": "new"}}, next())
+
+	kv.Delete("app/db/username", "app/db/password")
+	u := next()
+	assert.Nil(t, u.value)
+	assert.True(t, errors.Is(u.err, envsecret.ErrNotFound))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, updates)
+}
+
+func TestEtcd_WatchErrors(t *testing.T) {
+	kv := newFakeKV()
+	kv.Put(map[string]string{"app/db/password": "old"})
+	kv.failures = []error{errors.New("unavailable")}
+	kv.interrupts = []clientv3.WatchResponse{{Canceled: true}, {CompactRevision: 1}}
+
+	subject := secretstore.New(kv)
+
+	type update struct {
+		value map[string]interface{}
+		err   error
+	}
+	updates := make(chan update, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "app/db/", func(m map[string]interface{}, err error) {
+			updates <- update{m, err}
+		})
+	}()
+
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return update{}
+		}
+	}
+
+	old := update{value: map[string]interface{}{"password": "old"}}
+
+	// The failed first read is delivered and retried.
+	assert.Equal(t, update{err: errors.New("unavailable")}, next())
+	assert.Equal(t, old, next())
+
+	// The failed watch is delivered and retried.
+	u := next()
+	assert.Nil(t, u.value)
+	assert.Equal(t, rpctypes.ErrFutureRev, u.err)
+
+	// The compacted watch is read again and watched from the new revision.
+	assert.Equal(t, old, next())
+
+	kv.Put(map[string]string{"app/db/password": "new"})
+	assert.Equal(t, update{value: map[string]interface{}{"password": "new"}}, next())
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, updates)
+}
+
+// fakeKV is an in-memory stand-in for an etcd client, keeping every revision of the keyspace.
+type fakeKV struct {
+	mu        sync.Mutex
+	revisions []map[string]string
+	events    [][]*clientv3.Event
+	changed   chan struct{}
+
+	// failures are returned by the next calls to Get, and interrupts end the next calls to Watch.
+	failures   []error
+	interrupts []clientv3.WatchResponse
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{
+		revisions: []map[string]string{{}},
+		events:    [][]*clientv3.Event{nil},
+		changed:   make(chan struct{}),
+	}
+}
+
+func (f *fakeKV) Put(values map[string]string) {
+	f.commit(func(next map[string]string) (events []*clientv3.Event) {
+		for key, value := range values {
+			next[key] = value
+			events = append(events, &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}})
+		}
+		return
+	})
+}
+
+func (f *fakeKV) Delete(keys ...string) {
+	f.commit(func(next map[string]string) (events []*clientv3.Event) {
+		for _, key := range keys {
+			delete(next, key)
+			events = append(events, &clientv3.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(key)}})
+		}
+		return
+	})
+}
+
+func (f *fakeKV) commit(apply func(map[string]string) []*clientv3.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	next := make(map[string]string)
+	for key, value := range f.revisions[len(f.revisions)-1] {
+		next[key] = value
+	}
+	f.events = append(f.events, apply(next))
+	f.revisions = append(f.revisions, next)
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	op := clientv3.OpGet(key, opts...)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return nil, err
+	}
+
+	revision := op.Rev()
+	if revision == 0 {
+		revision = int64(len(f.revisions) - 1)
+	}
+
+	response := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: int64(len(f.revisions) - 1)}}
+	for k, v := range f.revisions[revision] {
+		if matches(op, k) {
+			response.Kvs = append(response.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+	sort.Slice(response.Kvs, func(i, j int) bool { return bytes.Compare(response.Kvs[i].Key, response.Kvs[j].Key) < 0 })
+
+	return response, nil
+}
+
+func (f *fakeKV) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	ch := make(chan clientv3.WatchResponse)
+
+	f.mu.Lock()
+	if len(f.interrupts) > 0 {
+		response := f.interrupts[0]
+		f.interrupts = f.interrupts[1:]
+		f.mu.Unlock()
+
+		go func() {
+			defer close(ch)
+			select {
+			case ch <- response:
+			case <-ctx.Done():
+			}
+		}()
+		return ch
+	}
+	f.mu.Unlock()
+
+	go func() {
+		defer close(ch)
+		for revision := op.Rev(); ; revision++ {
+			f.mu.Lock()
+			for revision >= int64(len(f.revisions)) {
+				changed := f.changed
+				f.mu.Unlock()
+				select {
+				case <-changed:
+				case <-ctx.Done():
+					return
+				}
+				f.mu.Lock()
+			}
+			response := clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: revision}}
+			for _, event := range f.events[revision] {
+				if matches(op, string(event.Kv.Key)) {
+					response.Events = append(response.Events, event)
+				}
+			}
+			f.mu.Unlock()
+
+			if len(response.Events) == 0 {
+				continue
+			}
+			select {
+			case ch <- response:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// matches reports whether the key is selected by the operation's key or range.
+func matches(op clientv3.Op, key string) bool {
+	if end := op.RangeBytes(); end != nil {
+		return key >= string(op.KeyBytes()) && key < string(end)
+	}
+	return key == string(op.KeyBytes())
+}
+
+func TestEtcd_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		values := make(map[string]string, len(fixtures))
+		for id, secret := range fixtures {
+			values[id] = string(storetest.Encode(secret))
+		}
+		kv := newFakeKV()
+		kv.Put(values)
+		return secretstore.New(kv)
+	}, storetest.IDs(func(name string) string {
+		return "app/" + name
+	}), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.Etcd).WithContext(ctx)
+	}))
+}
@@ -0,0 +1,164 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// DefaultTimeout bounds each command when Config.Timeout is zero.
+const DefaultTimeout = 10 * time.Second
+
+// maxStderr bounds the amount of standard error included in errors.
+const maxStderr = 1024
+
+// baseEnv names the environment variables always passed to the command.
+var baseEnv = []string{"PATH", "HOME", "TMPDIR", "USER"}
+
+// Config configures an ExecStore.
+type Config struct {
+	// Command is the path or name of the command to run.
+	Command string
+	// Args are passed to the command, with "{id}" replaced by the identifier. If no argument
+	// contains "{id}" and Stdin is false, the identifier is appended as the final argument
+	// following "--", so that an identifier starting with "-" is not read as a flag. Commands
+	// not accepting "--" should place "{id}" in Args instead.
+	Args []string
+	// Stdin, if true, writes the identifier followed by a newline to the command's standard input.
+	Stdin bool
+	// Timeout bounds each command, defaulting to DefaultTimeout.
+	Timeout time.Duration
+	// PassEnv names environment variables passed through to the command in addition to PATH,
+	// HOME, TMPDIR and USER. The rest of the environment is scrubbed.
+	PassEnv []string
+	// Env holds additional "KEY=value" environment variables for the command.
+	Env []string
+}
+
+// ExecStore retrieves secrets by running an external credential helper command, such as the
+// 1Password CLI, pass or a docker credential helper.
+type ExecStore struct {
+	config Config
+	ctx    context.Context
+}
+
+// New returns an ExecStore with the given configuration.
+func New(config Config) *ExecStore {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	return &ExecStore{
+		config: config,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the ExecStore which runs commands with the given context.
+func (s *ExecStore) WithContext(ctx context.Context) *ExecStore {
+	return &ExecStore{
+		config: s.config,
+		ctx:    ctx,
+	}
+}
+
+// Get runs the command for the given identifier and parses its output as a JSON object, as
+// several dotenv formatted KEY=value lines, or else as a single value. A single line is always
+// a single value, so that values such as padded base64 are not mistaken for dotenv.
+func (s *ExecStore) Get(id string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.config.Command, s.args(id)...)
+	cmd.Env = s.env()
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if s.config.Stdin {
+		cmd.Stdin = strings.NewReader(id + "\n")
+	}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := stderrMessage(stderr.Bytes()); msg != "" {
+			return nil, fmt.Errorf("running %s for %s: %w: %s", s.config.Command, id, err, msg)
+		}
+		return nil, fmt.Errorf("running %s for %s: %w", s.config.Command, id, err)
+	}
+
+	return parse(stdout.Bytes())
+}
+
+// args returns the command's arguments for the given identifier.
+func (s *ExecStore) args(id string) []string {
+	var (
+		args     = make([]string, len(s.config.Args))
+		replaced bool
+	)
+	for i, arg := range s.config.Args {
+		args[i] = strings.ReplaceAll(arg, "{id}", id)
+		replaced = replaced || args[i] != arg
+	}
+
+	if !replaced && !s.config.Stdin {
+		args = append(args, "--", id)
+	}
+
+	return args
+}
+
+// env returns the scrubbed environment for the command.
+func (s *ExecStore) env() []string {
+	var env []string
+	for _, name := range append(append([]string{}, baseEnv...), s.config.PassEnv...) {
+		if value, found := os.LookupEnv(name); found {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return append(env, s.config.Env...)
+}
+
+// parse the command's output.
+func parse(out []byte) (map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(out)
+	if len(trimmed) == 0 {
+		return nil, errors.New("command produced no output")
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(trimmed, &m); err == nil && m != nil {
+		return m, nil
+	}
+
+	if bytes.Contains(trimmed, []byte("\n")) {
+		if m, err := secretvalue.ParseEnv(trimmed); err == nil {
+			return m, nil
+		}
+	}
+
+	return map[string]interface{}{
+		secretvalue.Key: strings.TrimSuffix(string(out), "\n"),
+	}, nil
+}
+
+// stderrMessage returns the trimmed, truncated standard error of the command.
+func stderrMessage(stderr []byte) string {
+	msg := strings.TrimSpace(string(stderr))
+	if len(msg) > maxStderr {
+		msg = msg[:maxStderr] + "..."
+	}
+
+	return msg
+}
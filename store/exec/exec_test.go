@@ -0,0 +1,181 @@
+package exec_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/exec"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+const helperEnv = "ENVSECRET_EXEC_HELPER"
+
+// TestHelperProcess is run as the credential helper by the other tests.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	mode, id := args[0], ""
+	if len(args) > 2 && args[1] == "--" {
+		id = args[2]
+	} else if len(args) > 1 {
+		id = args[1]
+	} else {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		id = strings.TrimSpace(line)
+	}
+
+	switch mode {
+	case "json":
+		fmt.Printf("{\"id\":%q,\"username\":\"user\",\"password\":\"pass\"}\n", id)
+	case "dotenv":
+		fmt.Printf("ID=%s\nUSERNAME=user\nPASSWORD=pass\n", id)
+	case "plain":
+		fmt.Printf("secret for %s\n", id)
+	case "base64":
+		fmt.Println("c2VjcmV0==")
+	case "fixture":
+		fmt.Printf("%s\n", storetest.Encode(storetest.Fixtures()[id]))
+	case "env":
+		fmt.Printf("{\"leaked\":%q,\"extra\":%q}\n", os.Getenv("ENVSECRET_EXEC_LEAKED"), os.Getenv("ENVSECRET_EXEC_EXTRA"))
+	case "fail":
+		fmt.Fprintf(os.Stderr, "no credentials for %s\n", id)
+		os.Exit(1)
+	case "sleep":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func TestExecStore_Get(t *testing.T) {
+	t.Setenv("ENVSECRET_EXEC_LEAKED", "leaked")
+
+	helper := func(mode string, config secretstore.Config) secretstore.Config {
+		config.Command = os.Args[0]
+		config.Args = append([]string{"-test.run=TestHelperProcess", "--", mode}, config.Args...)
+		config.Env = append(config.Env, helperEnv+"=1")
+		return config
+	}
+
+	cases := []struct {
+		name     string
+		config   secretstore.Config
+		id       string
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			name:     "json output with appended argument",
+			config:   helper("json", secretstore.Config{}),
+			id:       "db-creds",
+			expected: map[string]interface{}{"id": "db-creds", "username": "user", "password": "pass"},
+		},
+		{
+			name:     "dotenv output with templated argument",
+			config:   helper("dotenv", secretstore.Config{Args: []string{"op://vault/{id}"}}),
+			id:       "db-creds",
+			expected: map[string]interface{}{"ID": "op://vault/db-creds", "USERNAME": "user", "PASSWORD": "pass"},
+		},
+		{
+			name:     "identifier starting with a dash",
+			config:   helper("json", secretstore.Config{}),
+			id:       "-db-creds",
+			expected: map[string]interface{}{"id": "-db-creds", "username": "user", "password": "pass"},
+		},
+		{
+			name:     "single line containing an equals sign",
+			config:   helper("base64", secretstore.Config{}),
+			id:       "token",
+			expected: map[string]interface{}{"value": "c2VjcmV0=="},
+		},
+		{
+			name:     "plain output with identifier on stdin",
+			config:   helper("plain", secretstore.Config{Stdin: true}),
+			id:       "api-key",
+			expected: map[string]interface{}{"value": "secret for api-key"},
+		},
+		{
+			name:     "scrubbed environment",
+			config:   helper("env", secretstore.Config{Env: []string{"ENVSECRET_EXEC_EXTRA=extra"}}),
+			id:       "id",
+			expected: map[string]interface{}{"leaked": "", "extra": "extra"},
+		},
+		{
+			name:     "passed environment",
+			config:   helper("env", secretstore.Config{PassEnv: []string{"ENVSECRET_EXEC_LEAKED"}}),
+			id:       "id",
+			expected: map[string]interface{}{"leaked": "leaked", "extra": ""},
+		},
+		{
+			name:   "failure includes stderr",
+			config: helper("fail", secretstore.Config{}),
+			id:     "db-creds",
+			err:    "exit status 1: no credentials for db-creds",
+		},
+		{
+			name:   "timeout",
+			config: helper("sleep", secretstore.Config{Timeout: 100 * time.Millisecond}),
+			id:     "db-creds",
+			err:    context.DeadlineExceeded.Error(),
+		},
+		{
+			name:   "missing command",
+			config: secretstore.Config{Command: "envsecret-no-such-helper"},
+			id:     "db-creds",
+			err:    "executable file not found",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(test.config)
+
+			actual, err := subject.Get(test.id)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, fmt.Sprint(err), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestExecStore_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	subject := secretstore.New(secretstore.Config{Command: os.Args[0]}).WithContext(ctx)
+
+	_, err := subject.Get("db-creds")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestExecStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, _ envsecret.Fixtures) envsecret.Store {
+		return secretstore.New(secretstore.Config{
+			Command: os.Args[0],
+			Args:    []string{"-test.run=TestHelperProcess", "--", "fixture"},
+			Env:     []string{helperEnv + "=1"},
+		})
+	}, storetest.SkipMissing(), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.ExecStore).WithContext(ctx)
+	}))
+}
@@ -0,0 +1,435 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// dataDir is the symlink Kubernetes atomically swaps when a mounted secret is updated.
+const dataDir = "..data"
+
+// maxAttempts bounds the number of times a directory is reread if it changes while being read.
+const maxAttempts = 5
+
+// ErrOutsideRoot is returned for identifiers resolving to a path outside the store's root.
+var ErrOutsideRoot = errors.New("path is outside of the store root")
+
+// FileStore reads secrets from files and directories, such as Kubernetes secret volumes and
+// Docker secrets mounted at /run/secrets.
+type FileStore struct {
+	root string
+}
+
+// New returns a FileStore reading paths beneath the given root directory.
+func New(root string) *FileStore {
+	return &FileStore{
+		root: root,
+	}
+}
+
+// Get reads the file or directory at the given path, relative to the store's root.
+//
+// A directory becomes a map of file name to file contents. A file is parsed according to its
+// extension as JSON (.json), YAML (.yaml, .yml) or dotenv (.env), and any other file is returned
+// as a single value.
+func (s *FileStore) Get(id string) (map[string]interface{}, error) {
+	path, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, wrap(id, err)
+	}
+
+	if info.IsDir() {
+		return readDir(path)
+	}
+
+	return readFile(path)
+}
+
+// List implements envsecret.ListStore, returning the path of every file beneath the given
+// directory, relative to the store's root. Hidden files and directories, such as the
+// "..data" directory of Kubernetes secret volumes, are skipped.
+func (s *FileStore) List(prefix string) ([]string, error) {
+	dir, err := s.resolve(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Kubernetes secret volumes hold symlinks to files, so follow them.
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, filepath.ToSlash(filepath.Join(prefix, rel)))
+
+		return nil
+	})
+	if err != nil {
+		return nil, wrap(prefix, err)
+	}
+
+	return ids, nil
+}
+
+// Put implements envsecret.WritableStore, writing the secret to the given path relative to the
+// store's root in the format Get reads it in. Identifiers ending in a separator, or naming an
+// existing directory, are written as a directory of one file per entry, removing the files of
+// entries no longer present. Files are replaced atomically and missing parent directories created.
+func (s *FileStore) Put(id string, secret map[string]interface{}) error {
+	path, err := s.resolveNew(id)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); (err == nil && info.IsDir()) || strings.HasSuffix(id, "/") {
+		return writeDir(path, secret)
+	}
+
+	b, err := marshal(path, secret)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", id, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	return writeFile(path, b)
+}
+
+// Delete implements envsecret.WritableStore, removing the file or directory at the given path
+// relative to the store's root.
+func (s *FileStore) Delete(id string) error {
+	path, err := s.resolve(id)
+	if err != nil {
+		return err
+	}
+
+	if path == s.absRoot() {
+		return fmt.Errorf("%w: cannot delete the root", ErrOutsideRoot)
+	}
+
+	return os.RemoveAll(path)
+}
+
+// writeDir writes one file per entry of the secret into the directory, then removes any other
+// files Get would read from it, leaving hidden entries in place.
+func writeDir(dir string, secret map[string]interface{}) error {
+	for name := range secret {
+		if !filepath.IsLocal(name) || strings.ContainsRune(name, filepath.Separator) || strings.HasPrefix(name, ".") {
+			return fmt.Errorf("invalid file name %q", name)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for name, v := range secret {
+		if err := writeFile(filepath.Join(dir, name), []byte(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, found := secret[entry.Name()]; found || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// absRoot returns the absolute path of the root, or the root as given should that fail.
+func (s *FileStore) absRoot() string {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return s.root
+	}
+	return root
+}
+
+// resolveNew resolves the identifier of a possibly missing path, ensuring its nearest existing
+// ancestor lies within the root after following symlinks.
+func (s *FileStore) resolveNew(id string) (string, error) {
+	path, err := s.resolve(id)
+	if err == nil || !errors.Is(err, envsecret.ErrNotFound) {
+		return path, err
+	}
+
+	root := s.absRoot()
+	path = id
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	for ancestor := filepath.Dir(path); ; ancestor = filepath.Dir(ancestor) {
+		resolved, err := filepath.EvalSymlinks(ancestor)
+		if errors.Is(err, os.ErrNotExist) && ancestor != filepath.Dir(ancestor) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		if !within(resolvedRoot, resolved) {
+			return "", fmt.Errorf("%w: %s", ErrOutsideRoot, id)
+		}
+		return path, nil
+	}
+}
+
+// resolve the identifier to a path, ensuring it lies within the root even after following symlinks.
+func (s *FileStore) resolve(id string) (string, error) {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return "", err
+	}
+
+	path := id
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	if !within(root, path) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, id)
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", wrap(id, err)
+	}
+
+	if !within(resolvedRoot, resolved) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, id)
+	}
+
+	return path, nil
+}
+
+// readDir reads every regular file in the directory into a map keyed by file name. Kubernetes
+// secret volumes are read through the resolved "..data" directory, and reread should it be
+// swapped in the meantime, so that every value comes from the same revision of the secret.
+func readDir(dir string) (map[string]interface{}, error) {
+	if _, err := os.Lstat(filepath.Join(dir, dataDir)); err != nil {
+		return readEntries(dir)
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		before, err := filepath.EvalSymlinks(filepath.Join(dir, dataDir))
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := readEntries(before)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		after, err := filepath.EvalSymlinks(filepath.Join(dir, dataDir))
+		if err != nil {
+			return nil, err
+		}
+
+		if before == after && m != nil {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("secret directory %s changed while being read", dir)
+}
+
+// readEntries reads every regular file in the directory, skipping hidden entries.
+func readEntries(dir string) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		} else if !info.Mode().IsRegular() {
+			continue
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		m[entry.Name()] = trim(b)
+	}
+
+	return m, nil
+}
+
+// readFile parses the file according to its extension.
+func readFile(path string) (map[string]interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, &m)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &m)
+	case ".env":
+		m, err = secretvalue.ParseEnv(b)
+	default:
+		return map[string]interface{}{
+			secretvalue.Key: trim(b),
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// marshal the secret in the format readFile parses according to the path's extension.
+func marshal(path string, secret map[string]interface{}) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return json.MarshalIndent(secret, "", "  ")
+	case ".yaml", ".yml":
+		return yaml.Marshal(secret)
+	case ".env":
+		return marshalEnv(secret)
+	}
+
+	v, found := secret[secretvalue.Key]
+	if !found || len(secret) != 1 {
+		return nil, fmt.Errorf("plain files hold a single %q entry", secretvalue.Key)
+	}
+
+	return []byte(fmt.Sprint(v)), nil
+}
+
+// marshalEnv writes the secret as sorted, quoted dotenv KEY=VALUE lines.
+func marshalEnv(secret map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(secret))
+	for key := range secret {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		value := fmt.Sprint(secret[key])
+		if key == "" || strings.ContainsAny(key, "= \t\n#") || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%q cannot be written as a dotenv line", key)
+		}
+
+		quote := `"`
+		if strings.Contains(value, quote) {
+			if quote = "'"; strings.Contains(value, quote) {
+				return nil, fmt.Errorf("%q cannot be written as a dotenv line", key)
+			}
+		}
+		b.WriteString(key + "=" + quote + value + quote + "\n")
+	}
+
+	return []byte(b.String()), nil
+}
+
+// writeFile atomically replaces the file at path with the given contents.
+func writeFile(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// trim a single trailing newline, as commonly written by editors and shell redirection.
+func trim(b []byte) string {
+	return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+}
+
+// within reports whether path is root or beneath it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// wrap translates missing file errors into envsecret.ErrNotFound.
+func wrap(id string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return err
+}
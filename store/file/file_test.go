@@ -0,0 +1,346 @@
+package file_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/file"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestFileStore_Get(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	write(t, root, "plain", "plain value\n")
+	write(t, root, "creds.json", `{"username":"user","password":"pass","port":5432}`)
+	write(t, root, "creds.yaml", "username: user\npassword: pass\n")
+	write(t, root, "creds.env", "USERNAME=user\nPASSWORD=pass\n")
+	write(t, root, "docker/db_password", "hunter2")
+	write(t, root, "docker/api_key", "abc123\n")
+	write(t, root, "docker/.hidden", "ignored")
+	write(t, outside, "escape", "should not be read")
+	if err := os.Symlink(filepath.Join(outside, "escape"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "plain file",
+			id:       "plain",
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "json file",
+			id:       "creds.json",
+			expected: map[string]interface{}{"username": "user", "password": "pass", "port": float64(5432)},
+		},
+		{
+			name:     "yaml file",
+			id:       "creds.yaml",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "dotenv file",
+			id:       "creds.env",
+			expected: map[string]interface{}{"USERNAME": "user", "PASSWORD": "pass"},
+		},
+		{
+			name:     "directory",
+			id:       "docker",
+			expected: map[string]interface{}{"db_password": "hunter2", "api_key": "abc123"},
+		},
+		{
+			name:     "absolute path within root",
+			id:       filepath.Join(root, "plain"),
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name: "missing file",
+			id:   "missing",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "relative traversal",
+			id:   "../" + filepath.Base(outside) + "/escape",
+			err:  secretstore.ErrOutsideRoot,
+		},
+		{
+			name: "absolute path outside root",
+			id:   filepath.Join(outside, "escape"),
+			err:  secretstore.ErrOutsideRoot,
+		},
+		{
+			name: "symlink outside root",
+			id:   "link",
+			err:  secretstore.ErrOutsideRoot,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(root)
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestFileStore_Get_Kubernetes(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "db-creds")
+
+	// Mimic the layout of a Kubernetes secret volume: keys are symlinks through "..data",
+	// which points at a timestamped directory holding the current revision.
+	write(t, dir, "..2019_03_01/username", "user")
+	write(t, dir, "..2019_03_01/password", "old")
+	symlink(t, "..2019_03_01", filepath.Join(dir, "..data"))
+	symlink(t, "..data/username", filepath.Join(dir, "username"))
+	symlink(t, "..data/password", filepath.Join(dir, "password"))
+
+	subject := secretstore.New(root)
+
+	actual, err := subject.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"username": "user", "password": "old"}, actual)
+
+	// Swap "..data" to a new revision as the kubelet does, and remove the old one.
+	write(t, dir, "..2019_03_02/username", "user")
+	write(t, dir, "..2019_03_02/password", "new")
+	symlink(t, "..2019_03_02", filepath.Join(dir, "..data_tmp"))
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "..2019_03_01")); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err = subject.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"username": "user", "password": "new"}, actual)
+
+	actual, err = subject.Get("db-creds/password")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "new"}, actual)
+}
+
+func TestFileStore_List(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "tenants/a.json", `{"key":"key-a"}`)
+	write(t, root, "tenants/nested/b", "key-b")
+	write(t, root, "tenants/.hidden", "ignored")
+	write(t, root, "tenants/.git/config", "ignored")
+	write(t, root, "other", "other")
+	write(t, root, "k8s/..2019_03_01/password", "pass")
+	symlink(t, "..2019_03_01", filepath.Join(root, "k8s", "..data"))
+	symlink(t, "..data/password", filepath.Join(root, "k8s", "password"))
+
+	subject := secretstore.New(root)
+
+	ids, err := subject.List("tenants")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenants/a.json", "tenants/nested/b"}, ids)
+
+	ids, err = subject.List("k8s/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k8s/password"}, ids)
+
+	ids, err = subject.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k8s/password", "other", "tenants/a.json", "tenants/nested/b"}, ids)
+
+	_, err = subject.List("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	_, err = subject.List("..")
+	assert.True(t, errors.Is(err, secretstore.ErrOutsideRoot))
+
+	spec := struct {
+		Tenants envsecret.Prefix
+	}{
+		Tenants: envsecret.NewPrefix("tenants"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"tenants/a.json":   {"key": "key-a"},
+		"tenants/nested/b": {"value": "key-b"},
+	}, spec.Tenants.Values)
+}
+
+func TestFileStore_Put(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	symlink(t, outside, filepath.Join(root, "link"))
+
+	subject := secretstore.New(root)
+
+	cases := []struct {
+		name     string
+		id       string
+		secret   map[string]interface{}
+		expected map[string]interface{}
+		err      bool
+	}{
+		{
+			name:     "plain file",
+			id:       "plain",
+			secret:   map[string]interface{}{"value": "plain value"},
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "json file in new directory",
+			id:       "nested/creds.json",
+			secret:   map[string]interface{}{"username": "user", "port": float64(5432)},
+			expected: map[string]interface{}{"username": "user", "port": float64(5432)},
+		},
+		{
+			name:     "yaml file",
+			id:       "creds.yaml",
+			secret:   map[string]interface{}{"username": "user", "password": "pass"},
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "dotenv file",
+			id:       "creds.env",
+			secret:   map[string]interface{}{"USERNAME": "user", "QUOTED": `say "hi"`},
+			expected: map[string]interface{}{"USERNAME": "user", "QUOTED": `say "hi"`},
+		},
+		{
+			name:     "directory",
+			id:       "docker/",
+			secret:   map[string]interface{}{"db_password": "hunter2", "port": 5432},
+			expected: map[string]interface{}{"db_password": "hunter2", "port": "5432"},
+		},
+		{
+			name:     "replaced directory",
+			id:       "docker/",
+			secret:   map[string]interface{}{"api_key": "abc123"},
+			expected: map[string]interface{}{"api_key": "abc123"},
+		},
+		{
+			name:   "hidden directory entry",
+			id:     "docker/",
+			secret: map[string]interface{}{".hidden": "x"},
+			err:    true,
+		},
+		{
+			name:   "plain file with several entries",
+			id:     "several",
+			secret: map[string]interface{}{"a": "1", "b": "2"},
+			err:    true,
+		},
+		{
+			name:   "multi-line dotenv value",
+			id:     "lines.env",
+			secret: map[string]interface{}{"KEY": "a\nb"},
+			err:    true,
+		},
+		{
+			name:   "directory entry outside",
+			id:     "docker/",
+			secret: map[string]interface{}{"../escape": "x"},
+			err:    true,
+		},
+		{
+			name:   "outside root",
+			id:     "../escape",
+			secret: map[string]interface{}{"value": "x"},
+			err:    true,
+		},
+		{
+			name:   "through symlink outside root",
+			id:     "link/new/escape",
+			secret: map[string]interface{}{"value": "x"},
+			err:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := subject.Put(c.id, c.secret)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			actual, err := subject.Get(c.id)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+
+	entries, err := os.ReadDir(outside)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = os.Stat(filepath.Join(filepath.Dir(root), "escape"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "plain", "value")
+	write(t, root, "docker/api_key", "abc123")
+
+	subject := secretstore.New(root)
+
+	assert.NoError(t, subject.Delete("plain"))
+	_, err := subject.Get("plain")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+
+	assert.NoError(t, subject.Delete("docker"))
+	_, err = subject.Get("docker")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+
+	assert.True(t, errors.Is(subject.Delete("plain"), envsecret.ErrNotFound))
+	assert.True(t, errors.Is(subject.Delete("."), secretstore.ErrOutsideRoot))
+	assert.True(t, errors.Is(subject.Delete("../other"), secretstore.ErrOutsideRoot))
+}
+
+func write(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, name string) {
+	t.Helper()
+	if err := os.Symlink(target, name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		root := t.TempDir()
+		for id, secret := range fixtures {
+			write(t, root, id, string(storetest.Encode(secret)))
+		}
+		return secretstore.New(root)
+	}, storetest.IDs(func(name string) string {
+		if name == storetest.Scalar {
+			return name
+		}
+		return name + ".json"
+	}))
+}
@@ -0,0 +1,177 @@
+package gcpsecretmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// DefaultEndpoint is the Secret Manager REST API endpoint used by NewHTTPClient.
+const DefaultEndpoint = "https://secretmanager.googleapis.com/v1/"
+
+// SecretManager provides access to Google Cloud Secret Manager.
+type SecretManager struct {
+	client  gcpSecretManager
+	project string
+	ctx     context.Context
+}
+
+// New returns a SecretManager instance configured to use the given client, resolving short
+// identifiers against the given default project.
+func New(client gcpSecretManager, project string) *SecretManager {
+	return &SecretManager{
+		client:  client,
+		project: project,
+		ctx:     context.Background(),
+	}
+}
+
+// WithContext returns a copy of the SecretManager which passes the given context to every request.
+func (s *SecretManager) WithContext(ctx context.Context) *SecretManager {
+	return &SecretManager{
+		client:  s.client,
+		project: s.project,
+		ctx:     ctx,
+	}
+}
+
+// Get accesses the secret version for the given identifier, either a full resource name such as
+// "projects/p/secrets/s/versions/latest" or a short "s@version" form resolved against the default
+// project. The version defaults to "latest" if omitted. The payload is parsed as a JSON object,
+// falling back to a single value map.
+func (s *SecretManager) Get(id string) (map[string]interface{}, error) {
+	name, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := s.client.AccessSecretVersion(s.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretvalue.Parse(payload), nil
+}
+
+// resolve the identifier into a full secret version resource name.
+func (s *SecretManager) resolve(id string) (string, error) {
+	if strings.HasPrefix(id, "projects/") {
+		parts := strings.Split(id, "/")
+		switch {
+		case len(parts) == 4 && parts[2] == "secrets":
+			return id + "/versions/latest", nil
+		case len(parts) == 6 && parts[2] == "secrets" && parts[4] == "versions":
+			return id, nil
+		}
+		return "", fmt.Errorf("invalid secret version name %q", id)
+	}
+
+	if s.project == "" {
+		return "", fmt.Errorf("no default project to resolve %q", id)
+	}
+
+	secret, version := id, "latest"
+	if i := strings.LastIndex(id, "@"); i >= 0 {
+		secret, version = id[:i], id[i+1:]
+	}
+
+	return fmt.Sprintf("projects/%s/secrets/%s/versions/%s", s.project, secret, version), nil
+}
+
+// HTTPClient accesses secret versions through the Secret Manager REST API.
+type HTTPClient struct {
+	client   *http.Client
+	endpoint string
+}
+
+// NewHTTPClient returns an HTTPClient sending requests with the given client, which is expected
+// to authenticate them, e.g. one returned by golang.org/x/oauth2/google.DefaultClient.
+func NewHTTPClient(client *http.Client) *HTTPClient {
+	return NewHTTPClientWithEndpoint(client, DefaultEndpoint)
+}
+
+// NewHTTPClientWithEndpoint returns an HTTPClient sending requests to the given endpoint.
+func NewHTTPClientWithEndpoint(client *http.Client, endpoint string) *HTTPClient {
+	return &HTTPClient{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/",
+	}
+}
+
+// AccessSecretVersion returns the payload of the named secret version, verifying its checksum.
+func (c *HTTPClient) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+name+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(name, resp)
+	}
+
+	var body struct {
+		Payload struct {
+			Data       string `json:"data"`
+			DataCrc32c string `json:"dataCrc32c"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+
+	if body.Payload.DataCrc32c != "" {
+		expected, err := strconv.ParseUint(body.Payload.DataCrc32c, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parsing payload checksum: %w", err)
+		}
+		if crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != uint32(expected) {
+			return nil, errors.New("payload checksum mismatch")
+		}
+	}
+
+	return data, nil
+}
+
+// statusError builds an error from an unsuccessful response, translating 404 into envsecret.ErrNotFound.
+func statusError(name string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, name)
+	}
+
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(b, &body) == nil && body.Error.Message != "" {
+		return fmt.Errorf("accessing %s: %s: %s", name, resp.Status, body.Error.Message)
+	}
+
+	return fmt.Errorf("accessing %s: %s", name, resp.Status)
+}
+
+type gcpSecretManager interface {
+	AccessSecretVersion(ctx context.Context, name string) ([]byte, error)
+}
@@ -0,0 +1,179 @@
+package gcpsecretmanager_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/gcpsecretmanager"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestSecretManager_Get(t *testing.T) {
+	versions := map[string]string{
+		"projects/default/secrets/db-creds/versions/latest": "{\"username\":\"user\",\"password\":\"pass\"}",
+		"projects/default/secrets/api-key/versions/latest":  "abc123",
+		"projects/default/secrets/api-key/versions/1":       "old123",
+		"projects/other/secrets/api-key/versions/latest":    "other123",
+		"projects/other/secrets/corrupt/versions/latest":    "corrupt",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error":{"code":401,"message":"missing credentials"}}`)
+			return
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")
+		data, found := versions[name]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":{"code":404,"message":"not found"}}`)
+			return
+		}
+
+		checksum := crc32.Checksum([]byte(data), crc32.MakeTable(crc32.Castagnoli))
+		if strings.Contains(name, "corrupt") {
+			checksum++
+		}
+		_, _ = fmt.Fprintf(w, `{"name":%q,"payload":{"data":%q,"dataCrc32c":"%d"}}`,
+			name, base64.StdEncoding.EncodeToString([]byte(data)), checksum)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: bearer("token")}
+	client := secretstore.NewHTTPClientWithEndpoint(httpClient, server.URL+"/v1")
+
+	cases := []struct {
+		name     string
+		id       string
+		project  string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "short json",
+			id:       "db-creds",
+			project:  "default",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "short plain",
+			id:       "api-key",
+			project:  "default",
+			expected: map[string]interface{}{"value": "abc123"},
+		},
+		{
+			name:     "short with version",
+			id:       "api-key@1",
+			project:  "default",
+			expected: map[string]interface{}{"value": "old123"},
+		},
+		{
+			name:     "full name",
+			id:       "projects/other/secrets/api-key/versions/latest",
+			project:  "default",
+			expected: map[string]interface{}{"value": "other123"},
+		},
+		{
+			name:     "full name without version",
+			id:       "projects/other/secrets/api-key",
+			expected: map[string]interface{}{"value": "other123"},
+		},
+		{
+			name:    "missing",
+			id:      "missing",
+			project: "default",
+			err:     envsecret.ErrNotFound,
+		},
+		{
+			name:    "checksum mismatch",
+			id:      "projects/other/secrets/corrupt",
+			project: "default",
+			err:     errors.New("payload checksum mismatch"),
+		},
+		{
+			name: "short without default project",
+			id:   "api-key",
+			err:  errors.New("no default project"),
+		},
+		{
+			name: "invalid full name",
+			id:   "projects/other/topics/api-key",
+			err:  errors.New("invalid secret version name"),
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client, test.project)
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.Error(t, err)
+				if !errors.Is(err, test.err) {
+					assert.Contains(t, fmt.Sprint(err), test.err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		subject := secretstore.New(secretstore.NewHTTPClientWithEndpoint(http.DefaultClient, server.URL+"/v1"), "default")
+
+		_, err := subject.Get("api-key")
+		assert.EqualError(t, err, "accessing projects/default/secrets/api-key/versions/latest: 401 Unauthorized: missing credentials")
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := secretstore.New(client, "default").WithContext(ctx).Get("api-key")
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestSecretManager_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/projects/default/secrets/"), "/versions/latest:access")
+			secret, found := fixtures[name]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, `{"error":{"code":404,"message":"not found"}}`)
+				return
+			}
+
+			data := storetest.Encode(secret)
+			_, _ = fmt.Fprintf(w, `{"name":%q,"payload":{"data":%q,"dataCrc32c":"%d"}}`,
+				name, base64.StdEncoding.EncodeToString(data), crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+		}))
+		t.Cleanup(server.Close)
+
+		return secretstore.New(secretstore.NewHTTPClientWithEndpoint(server.Client(), server.URL+"/v1"), "default")
+	}, storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.SecretManager).WithContext(ctx)
+	}))
+}
+
+type bearer string
+
+func (b bearer) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+string(b))
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// ErrInvalidPointer is returned when the configured JSON pointer does not resolve within a response.
+var ErrInvalidPointer = errors.New("json pointer does not resolve")
+
+// Config configures an HTTPStore.
+type Config struct {
+	// URL is a template for the secret's URL. "{id}" is replaced with the path escaped identifier
+	// and "{+id}" with the identifier as is, e.g. "https://broker.internal/secrets/{id}".
+	URL string
+	// Header is added to every request.
+	Header http.Header
+	// BearerToken, if set, is sent in the Authorization header of every request.
+	BearerToken string
+	// TLSConfig, if set, configures the client's TLS, e.g. with a client certificate for mTLS.
+	TLSConfig *tls.Config
+	// Client, if set, is used to send requests in place of one built from TLSConfig.
+	Client *http.Client
+	// Pointer is a JSON pointer (RFC 6901) to the payload within the response, e.g. "/data/secret".
+	// The whole response is used if empty.
+	Pointer string
+	// Errors maps response status codes to the errors returned for them. By default, 404 is
+	// mapped to envsecret.ErrNotFound.
+	Errors map[int]error
+}
+
+// HTTPStore retrieves secrets from an HTTP service returning JSON.
+type HTTPStore struct {
+	config Config
+	client *http.Client
+	ctx    context.Context
+}
+
+// New returns an HTTPStore with the given configuration.
+func New(config Config) *HTTPStore {
+	client := config.Client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config.TLSConfig
+		client = &http.Client{Transport: transport}
+	}
+
+	if config.Errors == nil {
+		config.Errors = map[int]error{
+			http.StatusNotFound: envsecret.ErrNotFound,
+		}
+	}
+
+	return &HTTPStore{
+		config: config,
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the HTTPStore which passes the given context to every request.
+func (s *HTTPStore) WithContext(ctx context.Context) *HTTPStore {
+	return &HTTPStore{
+		config: s.config,
+		client: s.client,
+		ctx:    ctx,
+	}
+}
+
+// Get requests the secret for the given identifier and returns the payload found at the
+// configured pointer. An object payload is returned as is, and any other payload as a single
+// value map.
+func (s *HTTPStore) Get(id string) (map[string]interface{}, error) {
+	u := strings.NewReplacer("{id}", url.PathEscape(id), "{+id}", id).Replace(s.config.URL)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range s.config.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if s.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BearerToken)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if mapped, found := s.config.Errors[resp.StatusCode]; found {
+			return nil, fmt.Errorf("%w: %s", mapped, id)
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("requesting %s: %s: %s", id, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	payload, err := resolve(body, s.config.Pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := payload.(map[string]interface{}); ok {
+		return m, nil
+	}
+
+	return map[string]interface{}{
+		secretvalue.Key: payload,
+	}, nil
+}
+
+// resolve the JSON pointer within the document.
+func resolve(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q must be empty or begin with /", ErrInvalidPointer, pointer)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescape.Replace(token)
+
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, found := node[token]
+			if !found {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPointer, pointer)
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPointer, pointer)
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPointer, pointer)
+		}
+	}
+
+	return doc, nil
+}
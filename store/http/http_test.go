@@ -0,0 +1,249 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/http"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+var errForbidden = errors.New("forbidden")
+
+func TestHTTPStore_Get(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(broker))
+	defer server.Close()
+
+	cases := []struct {
+		name     string
+		config   secretstore.Config
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "whole response",
+			config:   secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token"},
+			id:       "db-creds",
+			expected: map[string]interface{}{"data": map[string]interface{}{"username": "user", "password": "pass"}, "version": float64(3)},
+		},
+		{
+			name:     "pointer to object",
+			config:   secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "/data"},
+			id:       "db-creds",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "pointer to value",
+			config:   secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "/data/password"},
+			id:       "db-creds",
+			expected: map[string]interface{}{"value": "pass"},
+		},
+		{
+			name:     "pointer into array with escapes",
+			config:   secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "/items/1/a~1b"},
+			id:       "list",
+			expected: map[string]interface{}{"value": "second"},
+		},
+		{
+			name:     "escaped identifier",
+			config:   secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "/id"},
+			id:       "team/api-key",
+			expected: map[string]interface{}{"value": "team/api-key"},
+		},
+		{
+			name:     "unescaped identifier",
+			config:   secretstore.Config{URL: server.URL + "/{+id}", BearerToken: "token", Pointer: "/id"},
+			id:       "nested/path",
+			expected: map[string]interface{}{"value": "nested/path"},
+		},
+		{
+			name: "headers",
+			config: secretstore.Config{
+				URL:     server.URL + "/secrets/{id}",
+				Header:  http.Header{"Authorization": {"Bearer token"}, "X-Tenant": {"acme"}},
+				Pointer: "/tenant",
+			},
+			id:       "tenant",
+			expected: map[string]interface{}{"value": "acme"},
+		},
+		{
+			name:   "not found",
+			config: secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token"},
+			id:     "missing",
+			err:    envsecret.ErrNotFound,
+		},
+		{
+			name:   "mapped status",
+			config: secretstore.Config{URL: server.URL + "/secrets/{id}", Errors: map[int]error{http.StatusUnauthorized: errForbidden}},
+			id:     "db-creds",
+			err:    errForbidden,
+		},
+		{
+			name:   "unmapped status",
+			config: secretstore.Config{URL: server.URL + "/secrets/{id}"},
+			id:     "db-creds",
+			err:    errors.New("requesting db-creds: 401 Unauthorized: unauthorized"),
+		},
+		{
+			name:   "unresolved pointer",
+			config: secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "/data/missing"},
+			id:     "db-creds",
+			err:    secretstore.ErrInvalidPointer,
+		},
+		{
+			name:   "invalid pointer",
+			config: secretstore.Config{URL: server.URL + "/secrets/{id}", BearerToken: "token", Pointer: "data"},
+			id:     "db-creds",
+			err:    secretstore.ErrInvalidPointer,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(test.config)
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.Error(t, err)
+				if !errors.Is(err, test.err) {
+					assert.EqualError(t, err, test.err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestHTTPStore_Get_MutualTLS(t *testing.T) {
+	clientCert := certificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"client":%q}`, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	subject := secretstore.New(secretstore.Config{
+		URL:       server.URL + "/secrets/{id}",
+		TLSConfig: &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{clientCert}},
+	})
+
+	actual, err := subject.Get("db-creds")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"client": "envsecret-client"}, actual)
+
+	subject = secretstore.New(secretstore.Config{
+		URL:       server.URL + "/secrets/{id}",
+		TLSConfig: &tls.Config{RootCAs: rootCAs},
+	})
+
+	_, err = subject.Get("db-creds")
+	assert.Error(t, err)
+}
+
+func TestHTTPStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, found := fixtures[strings.TrimPrefix(r.URL.Path, "/secrets/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			var data interface{} = secret
+			if v, found := secret["value"]; found && len(secret) == 1 {
+				data = v
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		}))
+		t.Cleanup(server.Close)
+
+		return secretstore.New(secretstore.Config{
+			URL:     server.URL + "/secrets/{id}",
+			Pointer: "/data",
+			Errors:  map[int]error{http.StatusNotFound: envsecret.ErrNotFound},
+		})
+	}, storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.HTTPStore).WithContext(ctx)
+	}))
+}
+
+// broker is a stand-in for a credential broker.
+func broker(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, "unauthorized\n")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.EscapedPath(), "/secrets/")
+	switch {
+	case id == "db-creds":
+		_, _ = fmt.Fprint(w, `{"data":{"username":"user","password":"pass"},"version":3}`)
+	case id == "list":
+		_, _ = fmt.Fprint(w, `{"items":[{"a/b":"first"},{"a/b":"second"}]}`)
+	case id == "tenant":
+		_, _ = fmt.Fprintf(w, `{"tenant":%q}`, r.Header.Get("X-Tenant"))
+	case id == "team%2Fapi-key":
+		_, _ = fmt.Fprint(w, `{"id":"team/api-key"}`)
+	case r.URL.Path == "/nested/path":
+		_, _ = fmt.Fprint(w, `{"id":"nested/path"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// certificate generates a self-signed client certificate.
+func certificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "envsecret-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
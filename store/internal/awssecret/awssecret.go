@@ -0,0 +1,124 @@
+// Package awssecret holds the identifier and version rules shared by the AWS Secrets Manager stores.
+package awssecret
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// MaxBatchSize is the maximum number of identifiers accepted by a single BatchGetSecretValue call.
+const MaxBatchSize = 20
+
+// ID is an identifier split into the secret and the version stage or ID it selects, if any.
+type ID struct {
+	Secret    string
+	VersionID string
+	Stage     string
+}
+
+// Parse the identifier, splitting off a "#VERSION_ID" or "@STAGE" suffix. Since names may contain
+// "@", an "@" suffix is only a stage if it is non-empty and contains neither "/" nor ".".
+func Parse(id string) ID {
+	if i := strings.LastIndex(id, "#"); i >= 0 {
+		return ID{Secret: id[:i], VersionID: id[i+1:]}
+	} else if i := strings.LastIndex(id, "@"); i >= 0 && i < len(id)-1 && !strings.ContainsAny(id[i+1:], "/.") {
+		return ID{Secret: id[:i], Stage: id[i+1:]}
+	}
+	return ID{Secret: id}
+}
+
+// SelectsVersion reports whether the identifier selects a version stage or ID.
+func (id ID) SelectsVersion() bool {
+	return id.VersionID != "" || id.Stage != ""
+}
+
+// Describe the version retrieved for the identifier, labeled with the stage the identifier
+// selected, or else AWSCURRENT or the version's first stage.
+func Describe(id, versionID string, stages []string, created time.Time) envsecret.Metadata {
+	m := envsecret.Metadata{
+		Version: versionID,
+		Created: created,
+	}
+
+	if stage := Parse(id).Stage; stage != "" {
+		m.Stage = stage
+		return m
+	}
+	for _, stage := range stages {
+		if stage == "AWSCURRENT" {
+			m.Stage = stage
+			return m
+		}
+	}
+	if len(stages) > 0 {
+		m.Stage = stages[0]
+	}
+
+	return m
+}
+
+// Batch retrieves the secrets for the given identifiers in a single request into result, metadata
+// and errs, keyed by the identifiers matching each secret's name or full ARN.
+type Batch func(ids []string, result map[string]map[string]interface{}, metadata map[string]envsecret.Metadata, errs envsecret.BatchError) error
+
+// GetMany retrieves the secrets for the given identifiers with batch, in requests of at most
+// MaxBatchSize identifiers, falling back to get for identifiers selecting a version stage or ID,
+// given by partial ARN, or in a request that fails as a whole. Secrets that could not be retrieved
+// are reported with an envsecret.BatchError.
+func GetMany(ids []string, batch Batch, get func(id string) (map[string]interface{}, envsecret.Metadata, error)) (map[string]map[string]interface{}, map[string]envsecret.Metadata, error) {
+	var (
+		result     = make(map[string]map[string]interface{}, len(ids))
+		metadata   = make(map[string]envsecret.Metadata, len(ids))
+		errs       = make(envsecret.BatchError)
+		batched    []string
+		individual []string
+	)
+	for _, id := range ids {
+		if Parse(id).SelectsVersion() {
+			individual = append(individual, id)
+			continue
+		}
+		batched = append(batched, id)
+	}
+
+	for start := 0; start < len(batched); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(batched) {
+			end = len(batched)
+		}
+
+		// Should the whole request fail, e.g. for lack of the secretsmanager:BatchGetSecretValue
+		// permission, its secrets are retrieved individually below.
+		_ = batch(batched[start:end], result, metadata, errs)
+	}
+
+	// Secrets are matched to identifiers by name or full ARN, so those given by partial ARN are
+	// retrieved individually, as are any reported missing in case they were given that way.
+	for _, id := range batched {
+		if _, found := result[id]; found {
+			continue
+		} else if err, failed := errs[id]; failed && !errors.Is(err, envsecret.ErrNotFound) {
+			continue
+		}
+		delete(errs, id)
+		individual = append(individual, id)
+	}
+
+	for _, id := range individual {
+		m, md, err := get(id)
+		if err != nil {
+			errs[id] = err
+			continue
+		}
+		result[id], metadata[id] = m, md
+	}
+
+	if len(errs) > 0 {
+		return result, metadata, errs
+	}
+
+	return result, metadata, nil
+}
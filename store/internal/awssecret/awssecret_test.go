@@ -0,0 +1,99 @@
+package awssecret_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/awssecret"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       string
+		expected awssecret.ID
+	}{
+		{
+			name:     "name",
+			id:       "svc/db",
+			expected: awssecret.ID{Secret: "svc/db"},
+		},
+		{
+			name:     "stage",
+			id:       "svc/db@AWSPREVIOUS",
+			expected: awssecret.ID{Secret: "svc/db", Stage: "AWSPREVIOUS"},
+		},
+		{
+			name:     "version ID",
+			id:       "svc/db#v1",
+			expected: awssecret.ID{Secret: "svc/db", VersionID: "v1"},
+		},
+		{
+			name:     "email in name",
+			id:       "svc/ops@example.com",
+			expected: awssecret.ID{Secret: "svc/ops@example.com"},
+		},
+		{
+			name:     "at sign in name with stage",
+			id:       "svc/ops@admin@AWSCURRENT",
+			expected: awssecret.ID{Secret: "svc/ops@admin", Stage: "AWSCURRENT"},
+		},
+		{
+			name:     "trailing at sign",
+			id:       "svc/db@",
+			expected: awssecret.ID{Secret: "svc/db@"},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := awssecret.Parse(test.id)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.expected.Stage != "" || test.expected.VersionID != "", actual.SelectsVersion())
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		id       string
+		stages   []string
+		expected string
+	}{
+		{
+			name:     "selected stage",
+			id:       "svc/db@AWSPREVIOUS",
+			stages:   []string{"AWSCURRENT", "AWSPREVIOUS"},
+			expected: "AWSPREVIOUS",
+		},
+		{
+			name:     "current",
+			id:       "svc/db",
+			stages:   []string{"custom", "AWSCURRENT"},
+			expected: "AWSCURRENT",
+		},
+		{
+			name:     "first stage",
+			id:       "svc/db#v1",
+			stages:   []string{"custom", "AWSPENDING"},
+			expected: "custom",
+		},
+		{
+			name: "no stages",
+			id:   "svc/db#v1",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := awssecret.Describe(test.id, "v1", test.stages, created)
+			assert.Equal(t, envsecret.Metadata{Version: "v1", Stage: test.expected, Created: created}, actual)
+		})
+	}
+}
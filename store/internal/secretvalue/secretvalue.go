@@ -0,0 +1,49 @@
+// Package secretvalue holds helpers shared by the bundled stores.
+package secretvalue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Key is the map key under which non-JSON secret values are returned.
+const Key = "value"
+
+// Parse the raw secret as a JSON object, falling back to a single value map.
+func Parse(raw []byte) map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil || m == nil {
+		return map[string]interface{}{
+			Key: string(raw),
+		}
+	}
+
+	return m
+}
+
+// ParseEnv parses dotenv formatted KEY=VALUE lines into a map. Blank lines, comments and a
+// leading "export" are ignored, and values may be single or double quoted.
+func ParseEnv(raw []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for i, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.Index(line, "=")
+		if eq < 1 {
+			return nil, fmt.Errorf("parsing line %d: expected KEY=VALUE", i+1)
+		}
+
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+		if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+			value = value[1 : n-1]
+		}
+		m[key] = value
+	}
+
+	return m, nil
+}
@@ -0,0 +1,66 @@
+package secretvalue_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name     string
+		raw      string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "object",
+			raw:      "{\"key\":\"value\",\"port\":5432}",
+			expected: map[string]interface{}{"key": "value", "port": float64(5432)},
+		},
+		{
+			name:     "string",
+			raw:      "plain value",
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "json scalar",
+			raw:      "42",
+			expected: map[string]interface{}{"value": "42"},
+		},
+		{
+			name:     "json null",
+			raw:      "null",
+			expected: map[string]interface{}{"value": "null"},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, secretvalue.Parse([]byte(test.raw)))
+		})
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	raw := `
+# database credentials
+export USERNAME=user
+PASSWORD="pass word"
+HOST='db.internal'
+EMPTY=
+`
+
+	actual, err := secretvalue.ParseEnv([]byte(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"USERNAME": "user",
+		"PASSWORD": "pass word",
+		"HOST":     "db.internal",
+		"EMPTY":    "",
+	}, actual)
+
+	_, err = secretvalue.ParseEnv([]byte("not a pair"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package kms
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// KMS decrypts secrets held as AWS KMS ciphertext.
+type KMS struct {
+	client awsKMS
+}
+
+// New returns a KMS instance configured to use the given AWS KMS client.
+func New(client awsKMS) *KMS {
+	return &KMS{
+		client: client,
+	}
+}
+
+// Get decrypts the identifier, a base64 encoded ciphertext blob, optionally followed by the
+// encryption context as a query string, e.g. "AQICAHh...?service=billing&env=prod". The plaintext
+// is parsed as a JSON object, falling back to a single value map.
+func (k *KMS) Get(id string) (map[string]interface{}, error) {
+	input, err := parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := k.client.Decrypt(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretvalue.Parse(out.Plaintext), nil
+}
+
+// parse the identifier into a decrypt request.
+func parse(id string) (*kms.DecryptInput, error) {
+	encoded, query := id, ""
+	if i := strings.Index(id, "?"); i >= 0 {
+		encoded, query = id[:i], id[i+1:]
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decoding ciphertext: %w", err)
+	}
+
+	input := &kms.DecryptInput{
+		CiphertextBlob: blob,
+	}
+
+	if query != "" {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("parsing encryption context: %w", err)
+		}
+
+		input.EncryptionContext = make(map[string]*string, len(values))
+		for key := range values {
+			input.EncryptionContext[key] = aws.String(values.Get(key))
+		}
+	}
+
+	return input, nil
+}
+
+type awsKMS interface {
+	Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error)
+}
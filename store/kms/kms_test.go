@@ -0,0 +1,117 @@
+package kms_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/kms"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestKMS_Get(t *testing.T) {
+	client := &fakeKMS{}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      bool
+	}{
+		{
+			name:     "json plaintext",
+			id:       encrypt("{\"username\":\"user\",\"password\":\"pass\"}"),
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "plain plaintext",
+			id:       encrypt("hunter2"),
+			expected: map[string]interface{}{"value": "hunter2"},
+		},
+		{
+			name:     "encryption context",
+			id:       encrypt("hunter2", "env", "prod", "service", "billing") + "?service=billing&env=prod",
+			expected: map[string]interface{}{"value": "hunter2"},
+		},
+		{
+			name: "wrong encryption context",
+			id:   encrypt("hunter2", "env", "prod") + "?env=staging",
+			err:  true,
+		},
+		{
+			name: "missing encryption context",
+			id:   encrypt("hunter2", "env", "prod"),
+			err:  true,
+		},
+		{
+			name: "invalid base64",
+			id:   "not base64!",
+			err:  true,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client)
+
+			actual, err := subject.Get(test.id)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestKMS_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, _ envsecret.Fixtures) envsecret.Store {
+		return secretstore.New(&fakeKMS{})
+	}, storetest.IDs(func(name string) string {
+		return encrypt(string(storetest.Encode(storetest.Fixtures()[name])))
+	}), storetest.SkipMissing())
+}
+
+// encrypt builds a fake ciphertext of the plaintext bound to the given encryption context pairs.
+func encrypt(plaintext string, context ...string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(context); i += 2 {
+		buf.WriteString(context[i] + "=" + context[i+1] + ";")
+	}
+	buf.WriteString("|" + plaintext)
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+type fakeKMS struct{}
+
+func (*fakeKMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	parts := bytes.SplitN(input.CiphertextBlob, []byte("|"), 2)
+	if len(parts) != 2 {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "invalid ciphertext", nil)
+	}
+
+	var pairs int
+	for _, pair := range bytes.Split(parts[0], []byte(";")) {
+		if len(pair) == 0 {
+			continue
+		}
+		kv := bytes.SplitN(pair, []byte("="), 2)
+		if aws.StringValue(input.EncryptionContext[string(kv[0])]) != string(kv[1]) {
+			return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "encryption context mismatch", nil)
+		}
+		pairs++
+	}
+	if pairs != len(input.EncryptionContext) {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "encryption context mismatch", nil)
+	}
+
+	return &kms.DecryptOutput{Plaintext: parts[1]}, nil
+}
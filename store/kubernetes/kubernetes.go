@@ -0,0 +1,248 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// Kubernetes reads Secrets through the Kubernetes API.
+type Kubernetes struct {
+	client    k8sSecrets
+	namespace string
+	ctx       context.Context
+}
+
+// New returns a Kubernetes instance configured to use the given client, e.g. the CoreV1()
+// interface of a client-go clientset, resolving identifiers without a namespace against the
+// given default namespace.
+func New(client k8sSecrets, namespace string) *Kubernetes {
+	return &Kubernetes{
+		client:    client,
+		namespace: namespace,
+		ctx:       context.Background(),
+	}
+}
+
+// WithContext returns a copy of the Kubernetes store which passes the given context to every request.
+func (k *Kubernetes) WithContext(ctx context.Context) *Kubernetes {
+	return &Kubernetes{
+		client:    k.client,
+		namespace: k.namespace,
+		ctx:       ctx,
+	}
+}
+
+// Get retrieves the Secret for the given identifier, either "namespace/name" or a name in the
+// default namespace, and returns its data as a map of decoded strings.
+func (k *Kubernetes) Get(id string) (map[string]interface{}, error) {
+	namespace, name := k.parse(id)
+
+	secret, err := k.client.Secrets(namespace).Get(k.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, wrap(id, err)
+	}
+
+	return decode(secret), nil
+}
+
+// Watch calls fn with the current data of the Secret for the given identifier, and again whenever
+// the Secret changes, until the context is done. Should the Secret be deleted, fn is called with
+// envsecret.ErrNotFound. Should the watch fail, e.g. once its resource version has expired, the
+// Secret is read again after a backoff and watched from its new resource version. Should the watch
+// fail to start, e.g. while the API server is unavailable, fn is called with the error and the
+// watch is retried after a backoff.
+func (k *Kubernetes) Watch(ctx context.Context, id string, fn func(map[string]interface{}, error)) error {
+	namespace, name := k.parse(id)
+	w := &watcher{
+		secrets:  k.client.Secrets(namespace),
+		selector: fields.OneTermEqualSelector("metadata.name", name).String(),
+		id:       id,
+		name:     name,
+		fn:       fn,
+	}
+
+	var (
+		resourceVersion string
+		backoff         = minBackoff
+	)
+	for {
+		var current func()
+		if resourceVersion == "" {
+			secret, err := w.secrets.Get(ctx, name, metav1.GetOptions{})
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			} else if err != nil {
+				current = func() { w.fail(wrap(id, err)) }
+			} else {
+				resourceVersion = secret.ResourceVersion
+				current = func() { w.deliver(secret) }
+			}
+		}
+
+		events, err := w.secrets.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   w.selector,
+			ResourceVersion: resourceVersion,
+		})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		} else if err != nil {
+			resourceVersion = ""
+			w.fail(err)
+			if backoff, err = wait(ctx, backoff); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The watch is started before delivering the current data, so no change is missed.
+		if current != nil {
+			current()
+		}
+
+		var received int
+		resourceVersion, received, err = w.stream(ctx, events, resourceVersion)
+		events.Stop()
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if err != nil {
+			resourceVersion = ""
+		}
+		if err != nil || received == 0 {
+			if backoff, err = wait(ctx, backoff); err != nil {
+				return err
+			}
+		} else {
+			backoff = minBackoff
+		}
+	}
+}
+
+// Backoff bounds between failed or empty watches.
+const (
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// wait for the backoff or until the context is done, returning the next, doubled backoff.
+func wait(ctx context.Context, backoff time.Duration) (time.Duration, error) {
+	select {
+	case <-ctx.Done():
+		return backoff, ctx.Err()
+	case <-time.After(backoff):
+	}
+
+	if backoff *= 2; backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff, nil
+}
+
+// watcher delivers the changes to a single Secret, skipping those already delivered when the
+// Secret is read again after a failed watch.
+type watcher struct {
+	secrets  typedcorev1.SecretInterface
+	selector string
+	id, name string
+	fn       func(map[string]interface{}, error)
+
+	delivered string
+	missing   bool
+}
+
+// deliver the Secret's data, unless this version of it has already been delivered.
+func (w *watcher) deliver(secret *corev1.Secret) {
+	if !w.missing && secret.ResourceVersion != "" && secret.ResourceVersion == w.delivered {
+		return
+	}
+	w.delivered, w.missing = secret.ResourceVersion, false
+	w.fn(decode(secret), nil)
+}
+
+// fail delivers the error, unless the Secret was already reported missing.
+func (w *watcher) fail(err error) {
+	missing := errors.Is(err, envsecret.ErrNotFound)
+	if missing && w.missing {
+		return
+	}
+	w.delivered, w.missing = "", missing
+	w.fn(nil, err)
+}
+
+// stream delivers events from the watch until it closes or the context is done, returning the last
+// resource version seen, the number of events received and the error reported by the watch, if any.
+func (w *watcher) stream(ctx context.Context, events watch.Interface, resourceVersion string) (string, int, error) {
+	var received int
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, received, nil
+		case event, ok := <-events.ResultChan():
+			if !ok {
+				return resourceVersion, received, nil
+			}
+			received++
+
+			if event.Type == watch.Error {
+				return resourceVersion, received, apierrors.FromObject(event.Object)
+			}
+
+			secret, ok := event.Object.(*corev1.Secret)
+			if !ok || secret.Name != w.name {
+				continue
+			}
+			resourceVersion = secret.ResourceVersion
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				w.deliver(secret)
+			case watch.Deleted:
+				w.fail(fmt.Errorf("%w: %s", envsecret.ErrNotFound, w.id))
+			}
+		}
+	}
+}
+
+// parse the identifier into a namespace and name.
+func (k *Kubernetes) parse(id string) (string, string) {
+	if i := strings.Index(id, "/"); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return k.namespace, id
+}
+
+// decode the Secret's data into a map of strings.
+func decode(secret *corev1.Secret) map[string]interface{} {
+	m := make(map[string]interface{}, len(secret.Data))
+	for key, value := range secret.Data {
+		m[key] = string(value)
+	}
+
+	return m
+}
+
+// wrap translates missing Secret errors into envsecret.ErrNotFound.
+func wrap(id string, err error) error {
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return err
+}
+
+type k8sSecrets interface {
+	Secrets(namespace string) typedcorev1.SecretInterface
+}
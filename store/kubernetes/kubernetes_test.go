@@ -0,0 +1,270 @@
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/kubernetes"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestKubernetes_Get(t *testing.T) {
+	client := fake.NewClientset(
+		secret("default", "db-creds", map[string]string{"username": "user", "password": "pass"}),
+		secret("other", "api-key", map[string]string{"value": "abc123"}),
+	)
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "default namespace",
+			id:       "db-creds",
+			expected: map[string]interface{}{"username": "user", "password": "pass"},
+		},
+		{
+			name:     "explicit namespace",
+			id:       "other/api-key",
+			expected: map[string]interface{}{"value": "abc123"},
+		},
+		{
+			name: "missing",
+			id:   "api-key",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client.CoreV1(), "default")
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestKubernetes_Watch(t *testing.T) {
+	client := fake.NewClientset(
+		secret("default", "db-creds", map[string]string{"password": "old"}),
+	)
+	subject := secretstore.New(client.CoreV1(), "default")
+
+	type update struct {
+		value map[string]interface{}
+		err   error
+	}
+	updates := make(chan update, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "default/db-creds", func(m map[string]interface{}, err error) {
+			updates <- update{m, err}
+		})
+	}()
+
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return update{}
+		}
+	}
+
+	assert.Equal(t, update{value: map[string]interface{}{"password": "old"}}, next())
+
+	secrets := client.CoreV1().Secrets("default")
+	_, err := secrets.Create(ctx, secret("default", "unrelated", map[string]string{"key": "value"}), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = secrets.Update(ctx, secret("default", "db-creds", map[string]string{"password": "new"}), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, update{value: map[string]interface{}{"password": "new"}}, next())
+
+	assert.NoError(t, secrets.Delete(ctx, "db-creds", metav1.DeleteOptions{}))
+	u := next()
+	assert.Nil(t, u.value)
+	assert.True(t, errors.Is(u.err, envsecret.ErrNotFound))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestKubernetes_WatchError(t *testing.T) {
+	initial := secret("default", "db-creds", map[string]string{"password": "old"})
+	initial.ResourceVersion = "5"
+	client := fake.NewClientset(initial)
+
+	var (
+		mu               sync.Mutex
+		resourceVersions []string
+		gets             int
+		expired          = watch.NewFake()
+	)
+	client.PrependReactor("get", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gets++
+		return false, nil, nil
+	})
+	client.PrependWatchReactor("secrets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resourceVersions = append(resourceVersions, action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion)
+		if len(resourceVersions) == 1 {
+			return true, expired, nil
+		}
+		return false, nil, nil
+	})
+	subject := secretstore.New(client.CoreV1(), "default")
+
+	updates := make(chan map[string]interface{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "db-creds", func(m map[string]interface{}, err error) {
+			assert.NoError(t, err)
+			updates <- m
+		})
+	}()
+
+	next := func() map[string]interface{} {
+		select {
+		case m := <-updates:
+			return m
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return nil
+		}
+	}
+
+	assert.Equal(t, map[string]interface{}{"password": "old"}, next())
+
+	expired.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 410, Reason: metav1.StatusReasonExpired})
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(resourceVersions) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	updated := secret("default", "db-creds", map[string]string{"password": "new"})
+	updated.ResourceVersion = "6"
+	_, err := client.CoreV1().Secrets("default").Update(ctx, updated, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "new"}, next())
+	assert.Empty(t, updates, "the unchanged secret read after the error should not be delivered again")
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"5", "5"}, resourceVersions)
+	assert.Equal(t, 2, gets, "the secret should be read again after the watch failed")
+}
+
+func TestKubernetes_WatchStartError(t *testing.T) {
+	client := fake.NewClientset(
+		secret("default", "db-creds", map[string]string{"password": "old"}),
+	)
+
+	var (
+		mu      sync.Mutex
+		watches int
+	)
+	client.PrependWatchReactor("secrets", func(k8stesting.Action) (bool, watch.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if watches++; watches == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("apiserver is shutting down")
+		}
+		return false, nil, nil
+	})
+	subject := secretstore.New(client.CoreV1(), "default")
+
+	type update struct {
+		value map[string]interface{}
+		err   error
+	}
+	updates := make(chan update, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- subject.Watch(ctx, "db-creds", func(m map[string]interface{}, err error) {
+			updates <- update{m, err}
+		})
+	}()
+
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case err := <-done:
+			t.Fatalf("watch ended: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+		return update{}
+	}
+
+	u := next()
+	assert.Nil(t, u.value)
+	assert.True(t, apierrors.IsServiceUnavailable(u.err), "expected the watch error, got %v", u.err)
+	assert.Equal(t, update{value: map[string]interface{}{"password": "old"}}, next())
+
+	_, err := client.CoreV1().Secrets("default").Update(ctx, secret("default", "db-creds", map[string]string{"password": "new"}), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, update{value: map[string]interface{}{"password": "new"}}, next())
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, watches)
+}
+
+func TestKubernetes_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		var objects []runtime.Object
+		for id, s := range fixtures {
+			objects = append(objects, secret("default", id, storetest.Strings(s)))
+		}
+		return secretstore.New(fake.NewClientset(objects...).CoreV1(), "default")
+	}, storetest.StringValues())
+}
+
+func secret(namespace, name string, data map[string]string) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Data:       make(map[string][]byte, len(data)),
+	}
+	for key, value := range data {
+		s.Data[key] = []byte(value)
+	}
+
+	return s
+}
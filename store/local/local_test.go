@@ -5,7 +5,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/gavincabbage/envsecret"
 	secretstore "github.com/gavincabbage/envsecret/store/local"
+	"github.com/gavincabbage/envsecret/store/storetest"
 )
 
 func TestLocal_Get(t *testing.T) {
@@ -41,3 +43,11 @@ func TestLocal_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestLocal_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, _ envsecret.Fixtures) envsecret.Store {
+		return secretstore.New()
+	}, storetest.IDs(func(name string) string {
+		return string(storetest.Encode(storetest.Fixtures()[name]))
+	}), storetest.SkipMissing())
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// MemoryStore holds secrets in memory, for tests and local development. It is safe for
+// concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	secrets map[string]map[string]interface{}
+}
+
+// New returns a MemoryStore holding a copy of the given secrets, keyed by identifier.
+func New(secrets map[string]map[string]interface{}) *MemoryStore {
+	s := &MemoryStore{
+		secrets: make(map[string]map[string]interface{}, len(secrets)),
+	}
+	for id, m := range secrets {
+		s.secrets[id] = clone(m)
+	}
+
+	return s
+}
+
+// Get returns a copy of the secret with the given identifier.
+func (s *MemoryStore) Get(id string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, found := s.secrets[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	return clone(m), nil
+}
+
+// Put implements envsecret.WritableStore, storing a copy of the given secret.
+func (s *MemoryStore) Put(id string, secret map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.secrets[id] = clone(secret)
+
+	return nil
+}
+
+// Delete implements envsecret.WritableStore.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.secrets[id]; !found {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	delete(s.secrets, id)
+
+	return nil
+}
+
+// List implements envsecret.ListStore, returning every identifier beginning with the given prefix.
+func (s *MemoryStore) List(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id := range s.secrets {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// clone deeply copies the nested maps and slices of a secret so callers cannot modify the store.
+func clone(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = cloneValue(v)
+	}
+
+	return c
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return clone(v)
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = cloneValue(e)
+		}
+		return c
+	}
+
+	return v
+}
@@ -0,0 +1,80 @@
+package memory_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/memory"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	seed := map[string]map[string]interface{}{
+		"db": {"username": "user", "password": "pass", "nested": map[string]interface{}{"a": []interface{}{"b"}}},
+	}
+	subject := secretstore.New(seed)
+
+	seed["db"]["username"] = "changed"
+	actual, err := subject.Get("db")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", actual["username"])
+
+	actual["nested"].(map[string]interface{})["a"].([]interface{})[0] = "changed"
+	actual, err = subject.Get("db")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{"b"}}, actual["nested"])
+
+	assert.NoError(t, subject.Put("token", map[string]interface{}{"value": "abc"}))
+	actual, err = subject.Get("token")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "abc"}, actual)
+
+	assert.NoError(t, subject.Delete("token"))
+	_, err = subject.Get("token")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.True(t, errors.Is(subject.Delete("token"), envsecret.ErrNotFound))
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	subject := secretstore.New(map[string]map[string]interface{}{
+		"tenants/b": {"value": "b"},
+		"tenants/a": {"value": "a"},
+		"other":     {"value": "other"},
+	})
+
+	ids, err := subject.List("tenants/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenants/a", "tenants/b"}, ids)
+
+	ids, err = subject.List("none/")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	subject := secretstore.New(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("secret-%d", i%5)
+			_ = subject.Put(id, map[string]interface{}{"value": i})
+			_, _ = subject.Get(id)
+			_ = subject.Delete(id)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMemoryStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		return secretstore.New(fixtures)
+	})
+}
@@ -0,0 +1,180 @@
+package onepassword
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// Connect reads items from a 1Password Connect server.
+type Connect struct {
+	url    string
+	token  string
+	client *http.Client
+	ctx    context.Context
+}
+
+// New returns a Connect instance for the server at the given URL, authenticating with the given
+// Connect access token.
+func New(serverURL, token string) *Connect {
+	return NewWithClient(serverURL, token, http.DefaultClient)
+}
+
+// NewWithClient returns a Connect instance sending requests with the given HTTP client.
+func NewWithClient(serverURL, token string, client *http.Client) *Connect {
+	return &Connect{
+		url:    strings.TrimSuffix(serverURL, "/"),
+		token:  token,
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the Connect store which passes the given context to every request.
+func (c *Connect) WithContext(ctx context.Context) *Connect {
+	return &Connect{
+		url:    c.url,
+		token:  c.token,
+		client: c.client,
+		ctx:    ctx,
+	}
+}
+
+// Get retrieves the item for the given identifier, "vault/item[/field]", where vault and item are
+// names or IDs. The item's fields are returned keyed by label, and its username and password
+// fields additionally as "username" and "password" so that envsecret.Login can decode them. If a
+// field is given, only its value is returned.
+func (c *Connect) Get(id string) (map[string]interface{}, error) {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid identifier %q, expected vault/item[/field]", id)
+	}
+
+	vaultID, err := c.lookup("vaults", "name", parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	itemID, err := c.lookup("vaults/"+vaultID+"/items", "title", parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct {
+		Fields []struct {
+			ID      string `json:"id"`
+			Label   string `json:"label"`
+			Value   string `json:"value"`
+			Purpose string `json:"purpose"`
+		} `json:"fields"`
+	}
+	if err := c.get("vaults/"+vaultID+"/items/"+itemID, nil, &item); err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 3 {
+		for _, field := range item.Fields {
+			if field.Label == parts[2] || field.ID == parts[2] {
+				return map[string]interface{}{
+					secretvalue.Key: field.Value,
+				}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+
+	m := make(map[string]interface{}, len(item.Fields))
+	for _, field := range item.Fields {
+		if field.Label != "" {
+			m[field.Label] = field.Value
+		}
+		switch field.Purpose {
+		case "USERNAME":
+			m["username"] = field.Value
+		case "PASSWORD":
+			m["password"] = field.Value
+		}
+	}
+
+	return m, nil
+}
+
+// lookup the ID of the vault or item with the given name or ID in the collection at path.
+func (c *Connect) lookup(path, attribute, name string) (string, error) {
+	var objects []struct {
+		ID string `json:"id"`
+	}
+	query := url.Values{"filter": {fmt.Sprintf("%s eq %q", attribute, name)}}
+	if err := c.get(path, query, &objects); err != nil {
+		return "", err
+	}
+
+	switch len(objects) {
+	case 0:
+		var object struct {
+			ID string `json:"id"`
+		}
+		if err := c.get(path+"/"+url.PathEscape(name), nil, &object); err != nil {
+			return "", err
+		}
+		return object.ID, nil
+	case 1:
+		return objects[0].ID, nil
+	}
+
+	return "", fmt.Errorf("%q matches %d objects, use an ID", name, len(objects))
+}
+
+// get sends an authenticated request for the resource at path and decodes the response into v.
+func (c *Connect) get(path string, query url.Values, v interface{}) error {
+	u := c.url + "/v1/" + path
+	if query != nil {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(path, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// statusError builds an error from an unsuccessful response, translating 404 into envsecret.ErrNotFound.
+func statusError(path string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, path)
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(b, &body) == nil && body.Message != "" {
+		return fmt.Errorf("getting %s: %s: %s", path, resp.Status, body.Message)
+	}
+
+	return fmt.Errorf("getting %s: %s", path, resp.Status)
+}
@@ -0,0 +1,219 @@
+package onepassword_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/onepassword"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestConnect_Get(t *testing.T) {
+	server := httptest.NewServer(connect)
+	defer server.Close()
+
+	subject := secretstore.New(server.URL, "token")
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name: "login item",
+			id:   "Shared/Vendor Portal",
+			expected: map[string]interface{}{
+				"username": "vendor",
+				"password": "hunter2",
+				"user":     "vendor",
+				"pass":     "hunter2",
+				"api key":  "abc123",
+			},
+		},
+		{
+			name:     "field by label",
+			id:       "Shared/Vendor Portal/api key",
+			expected: map[string]interface{}{"value": "abc123"},
+		},
+		{
+			name:     "field by ID",
+			id:       "Shared/Vendor Portal/password",
+			expected: map[string]interface{}{"value": "hunter2"},
+		},
+		{
+			name:     "vault and item IDs",
+			id:       "v1/i1/username",
+			expected: map[string]interface{}{"value": "vendor"},
+		},
+		{
+			name: "unknown vault",
+			id:   "Private/Vendor Portal",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown item",
+			id:   "Shared/Other",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "unknown field",
+			id:   "Shared/Vendor Portal/other",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := subject.Get(c.id)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err), "expected %v, got %v", c.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestConnect_Get_Login(t *testing.T) {
+	server := httptest.NewServer(connect)
+	defer server.Close()
+
+	spec := struct {
+		Vendor envsecret.Login
+	}{
+		Vendor: envsecret.NewLogin("Shared/Vendor Portal"),
+	}
+
+	assert.NoError(t, envsecret.Process(&spec, secretstore.New(server.URL, "token")))
+	assert.Equal(t, "vendor", spec.Vendor.Username)
+	assert.Equal(t, "hunter2", spec.Vendor.Password)
+}
+
+func TestConnect_Get_Errors(t *testing.T) {
+	server := httptest.NewServer(connect)
+	defer server.Close()
+
+	_, err := secretstore.New(server.URL, "wrong").Get("Shared/Vendor Portal")
+	assert.EqualError(t, err, "getting vaults: 401 Unauthorized: Invalid token signature")
+
+	_, err = secretstore.New(server.URL, "token").Get("Shared")
+	assert.Error(t, err)
+
+	_, err = secretstore.New(server.URL, "token").Get("Shared/Locked")
+	assert.EqualError(t, err, "getting vaults/v1/items/Locked: 403 Forbidden: Access denied")
+	assert.False(t, errors.Is(err, envsecret.ErrNotFound))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = secretstore.New(server.URL, "token").WithContext(ctx).Get("Shared/Vendor Portal")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestConnect_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		vault := map[string]interface{}{"id": "v1", "name": "Test"}
+		items := make(map[string]map[string]interface{}, len(fixtures))
+		for id, secret := range fixtures {
+			title := strings.TrimPrefix(id, "Test/")
+			var fields []map[string]interface{}
+			for label, value := range storetest.Strings(secret) {
+				fields = append(fields, map[string]interface{}{"id": label, "label": label, "value": value})
+			}
+			items[title] = map[string]interface{}{"id": title, "title": title, "fields": fields}
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, "/v1/")
+			title := strings.TrimPrefix(path, "vaults/v1/items/")
+
+			var v interface{}
+			switch {
+			case path == "vaults":
+				v = filter(r, "name", vault)
+			case path == "vaults/v1/items":
+				v = []map[string]interface{}{}
+				for _, item := range items {
+					if found := filter(r, "title", item); len(found) > 0 {
+						v = found
+					}
+				}
+			case items[title] != nil:
+				v = items[title]
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 404, "message": "not found"})
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(v)
+		}))
+		t.Cleanup(server.Close)
+
+		return secretstore.NewWithClient(server.URL, "token", server.Client())
+	}, storetest.IDs(func(name string) string {
+		return "Test/" + name
+	}), storetest.StringValues(), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.Connect).WithContext(ctx)
+	}))
+}
+
+// connect is a minimal stand-in for the 1Password Connect API holding one vault and one item.
+var connect = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 401, "message": "Invalid token signature"})
+		return
+	}
+
+	vault := map[string]interface{}{"id": "v1", "name": "Shared"}
+	item := map[string]interface{}{
+		"id":    "i1",
+		"title": "Vendor Portal",
+		"vault": map[string]interface{}{"id": "v1"},
+		"fields": []map[string]interface{}{
+			{"id": "username", "label": "user", "purpose": "USERNAME", "value": "vendor"},
+			{"id": "password", "label": "pass", "purpose": "PASSWORD", "value": "hunter2"},
+			{"id": "f3", "label": "api key", "type": "CONCEALED", "value": "abc123"},
+		},
+	}
+
+	var v interface{}
+	switch strings.TrimPrefix(r.URL.Path, "/v1/") {
+	case "vaults":
+		v = filter(r, "name", vault)
+	case "vaults/v1":
+		v = vault
+	case "vaults/v1/items":
+		v = filter(r, "title", item)
+	case "vaults/v1/items/i1":
+		v = item
+	case "vaults/v1/items/Locked":
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 403, "message": "Access denied"})
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 404, "message": "not found"})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(v)
+})
+
+// filter applies a Connect `attribute eq "value"` filter to a single-object collection.
+func filter(r *http.Request, attribute string, object map[string]interface{}) []map[string]interface{} {
+	if r.URL.Query().Get("filter") == attribute+` eq "`+object[attribute].(string)+`"` {
+		return []map[string]interface{}{object}
+	}
+	return []map[string]interface{}{}
+}
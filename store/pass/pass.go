@@ -0,0 +1,200 @@
+package pass
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// DirEnv names the environment variable pass itself uses to override the store directory.
+const DirEnv = "PASSWORD_STORE_DIR"
+
+// ErrOutsideStore is returned for identifiers resolving to a path outside the password store.
+var ErrOutsideStore = errors.New("path is outside of the password store")
+
+// Decrypter decrypts the OpenPGP messages pass stores entries as.
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// PassStore reads entries from a pass password store.
+type PassStore struct {
+	dir       string
+	decrypter Decrypter
+}
+
+// New returns a PassStore reading entries beneath the given directory with the given Decrypter.
+func New(dir string, decrypter Decrypter) *PassStore {
+	return &PassStore{
+		dir:       dir,
+		decrypter: decrypter,
+	}
+}
+
+// Default returns a PassStore reading the directory pass would, $PASSWORD_STORE_DIR or else
+// ~/.password-store, and decrypting with the local gpg keyring.
+func Default() (*PassStore, error) {
+	dir := os.Getenv(DirEnv)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, ".password-store")
+	}
+
+	return New(dir, GPG{}), nil
+}
+
+// Get decrypts the entry for the given identifier, e.g. "work/db" for work/db.gpg. Following
+// pass conventions, the first line of the entry is returned as "value" and each later
+// "key: value" line as an additional entry. The first line is also returned as "password", and a
+// "login" or "user" line as "username", unless the entry sets them itself, so that
+// envsecret.Login can decode typical website entries.
+func (s *PassStore) Get(id string) (map[string]interface{}, error) {
+	if !filepath.IsLocal(filepath.FromSlash(id)) {
+		return nil, fmt.Errorf("%w: %s", ErrOutsideStore, id)
+	}
+
+	ciphertext, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(id)+".gpg"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.decrypter.Decrypt(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", id, err)
+	}
+
+	return parse(plaintext), nil
+}
+
+// parse a decrypted entry.
+func parse(plaintext []byte) map[string]interface{} {
+	scanner := bufio.NewScanner(bytes.NewReader(plaintext))
+	scanner.Buffer(nil, len(plaintext)+1)
+
+	m := make(map[string]interface{})
+	if !scanner.Scan() {
+		return map[string]interface{}{secretvalue.Key: ""}
+	}
+	m[secretvalue.Key] = strings.TrimSuffix(scanner.Text(), "\r")
+
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if key = strings.TrimSpace(key); !found || key == "" || strings.ContainsAny(key, " \t") {
+			continue
+		}
+		if _, dup := m[key]; !dup {
+			m[key] = strings.TrimSpace(value)
+		}
+	}
+
+	if _, found := m["password"]; !found {
+		m["password"] = m[secretvalue.Key]
+	}
+	if _, found := m["username"]; !found {
+		for _, alias := range []string{"login", "user"} {
+			if v, found := m[alias]; found {
+				m["username"] = v
+				break
+			}
+		}
+	}
+
+	return m
+}
+
+// GPG decrypts entries by running gpg, and so uses the local keyring and gpg-agent as pass does.
+type GPG struct {
+	// Command defaults to "gpg".
+	Command string
+	// Args are passed to gpg before the decryption arguments, e.g. "--homedir", "/path".
+	Args []string
+}
+
+// Decrypt implements Decrypter.
+func (g GPG) Decrypt(ciphertext []byte) ([]byte, error) {
+	command := g.Command
+	if command == "" {
+		command = "gpg"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, append(append([]string{}, g.Args...), "--quiet", "--batch", "--yes", "--decrypt")...)
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("running %s: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("running %s: %w", command, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// KeyRing decrypts entries in process with OpenPGP private keys, e.g. for tests and CI where no
+// gpg installation is available.
+type KeyRing struct {
+	keys openpgp.EntityList
+}
+
+// ReadKeyRing reads the armored or binary OpenPGP private keys in the file at the given path,
+// unlocking them with the passphrase if they are protected.
+func ReadKeyRing(path string, passphrase []byte) (*KeyRing, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := openpgp.ReadKeyRing(dearmor(b))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	for _, key := range keys {
+		if err := key.DecryptPrivateKeys(passphrase); err != nil {
+			return nil, fmt.Errorf("unlocking %s: %w", path, err)
+		}
+	}
+
+	return &KeyRing{
+		keys: keys,
+	}, nil
+}
+
+// Decrypt implements Decrypter.
+func (k *KeyRing) Decrypt(ciphertext []byte) ([]byte, error) {
+	md, err := openpgp.ReadMessage(dearmor(ciphertext), k.keys, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(md.UnverifiedBody)
+}
+
+// dearmor returns a reader of the binary form of possibly armored OpenPGP data.
+func dearmor(b []byte) io.Reader {
+	if block, err := armor.Decode(bytes.NewReader(b)); err == nil {
+		return block.Body
+	}
+
+	return bytes.NewReader(b)
+}
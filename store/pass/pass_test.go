@@ -0,0 +1,206 @@
+package pass_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/pass"
+)
+
+var entries = map[string]string{
+	"email/personal":   "hunter2\nlogin: me@example.com\nurl: https://mail.example.com\n",
+	"db/local":         "pw\nusername: admin\npassword: other\nNotes that are not a field.\n",
+	"api-token":        "abc123\n",
+	"work/vendor/blob": "line one\r\nkey: value\r\n",
+}
+
+func TestPassStore_Get(t *testing.T) {
+	dir, key := passwordStore(t, nil)
+
+	keyRing, err := secretstore.ReadKeyRing(key, nil)
+	assert.NoError(t, err)
+
+	testGet(t, secretstore.New(dir, keyRing))
+}
+
+func TestPassStore_Get_Passphrase(t *testing.T) {
+	dir, key := passwordStore(t, []byte("correct horse"))
+
+	_, err := secretstore.ReadKeyRing(key, []byte("wrong"))
+	assert.Error(t, err)
+
+	keyRing, err := secretstore.ReadKeyRing(key, []byte("correct horse"))
+	assert.NoError(t, err)
+
+	actual, err := secretstore.New(dir, keyRing).Get("api-token")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "abc123", "password": "abc123"}, actual)
+}
+
+func TestPassStore_Get_GPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	dir, key := passwordStore(t, nil)
+
+	home := t.TempDir()
+	out, err := exec.Command("gpg", "--homedir", home, "--batch", "--import", key).CombinedOutput()
+	if err != nil {
+		t.Skipf("importing key into gpg: %v: %s", err, out)
+	}
+
+	testGet(t, secretstore.New(dir, secretstore.GPG{Args: []string{"--homedir", home}}))
+
+	_, err = secretstore.New(dir, secretstore.GPG{Args: []string{"--homedir", t.TempDir()}}).Get("api-token")
+	assert.Error(t, err)
+}
+
+func TestDefault(t *testing.T) {
+	t.Setenv(secretstore.DirEnv, t.TempDir())
+
+	subject, err := secretstore.Default()
+	assert.NoError(t, err)
+
+	_, err = subject.Get("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+func testGet(t *testing.T, subject *secretstore.PassStore) {
+	t.Helper()
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name: "login alias",
+			id:   "email/personal",
+			expected: map[string]interface{}{
+				"value":    "hunter2",
+				"password": "hunter2",
+				"login":    "me@example.com",
+				"username": "me@example.com",
+				"url":      "https://mail.example.com",
+			},
+		},
+		{
+			name: "explicit fields",
+			id:   "db/local",
+			expected: map[string]interface{}{
+				"value":    "pw",
+				"username": "admin",
+				"password": "other",
+			},
+		},
+		{
+			name:     "password only",
+			id:       "api-token",
+			expected: map[string]interface{}{"value": "abc123", "password": "abc123"},
+		},
+		{
+			name:     "armored with CRLF",
+			id:       "work/vendor/blob",
+			expected: map[string]interface{}{"value": "line one", "password": "line one", "key": "value"},
+		},
+		{
+			name: "not found",
+			id:   "missing",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "outside store",
+			id:   "../api-token",
+			err:  secretstore.ErrOutsideStore,
+		},
+		{
+			name: "absolute path",
+			id:   "/etc/passwd",
+			err:  secretstore.ErrOutsideStore,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := subject.Get(c.id)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err), "expected %v, got %v", c.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+
+	spec := struct {
+		Email envsecret.Login
+		Token envsecret.String
+	}{
+		Email: envsecret.NewLogin("email/personal"),
+		Token: envsecret.NewString("api-token"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, "me@example.com", spec.Email.Username)
+	assert.Equal(t, "hunter2", spec.Email.Password)
+	assert.Equal(t, "abc123", spec.Token.Value)
+}
+
+// passwordStore creates a password store of the test entries encrypted to a new key, returning
+// its directory and the path of the armored private key, protected with the passphrase if given.
+func passwordStore(t *testing.T, passphrase []byte) (string, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("envsecret", "test", "envsecret@example.com", nil)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	for id, plaintext := range entries {
+		var buf bytes.Buffer
+		w, err := openpgp.Encrypt(&buf, []*openpgp.Entity{entity}, nil, nil, nil)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(plaintext))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+
+		ciphertext := buf.Bytes()
+		if id == "work/vendor/blob" {
+			var armored bytes.Buffer
+			aw, err := armor.Encode(&armored, "PGP MESSAGE", nil)
+			assert.NoError(t, err)
+			_, err = aw.Write(ciphertext)
+			assert.NoError(t, err)
+			assert.NoError(t, aw.Close())
+			ciphertext = armored.Bytes()
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(id)+".gpg")
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		assert.NoError(t, os.WriteFile(path, ciphertext, 0o600))
+	}
+
+	if passphrase != nil {
+		assert.NoError(t, entity.EncryptPrivateKeys(passphrase, nil))
+	}
+
+	var key bytes.Buffer
+	aw, err := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, entity.SerializePrivateWithoutSigning(aw, nil))
+	assert.NoError(t, aw.Close())
+
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	assert.NoError(t, os.WriteFile(keyPath, key.Bytes(), 0o600))
+
+	return dir, keyPath
+}
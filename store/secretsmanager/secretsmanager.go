@@ -2,41 +2,264 @@ package secretsmanager
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/awssecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
 )
 
 // SecretsManager provides access to AWS Secrets Manager.
 type SecretsManager struct {
-	client awsSecretsManager
+	client      awsSecretsManager
+	forceDelete bool
+}
+
+// Version describes the version of a secret returned by AWS Secrets Manager.
+type Version struct {
+	ID      string
+	Stages  []string
+	Created time.Time
 }
 
-// NewSecretsManager returns a SecretsManager instance configured to use the given AWS Secrets Manager client.
+// New returns a SecretsManager instance configured to use the given AWS Secrets Manager client.
 func New(sm awsSecretsManager) *SecretsManager {
 	return &SecretsManager{
 		client: sm,
 	}
 }
 
+// WithForceDelete returns a copy of the SecretsManager whose Delete removes secrets immediately,
+// without a recovery window, so that they may be recreated at once. It is intended for tests
+// against disposable accounts or emulators, since deleted secrets cannot be restored.
+func (s *SecretsManager) WithForceDelete() *SecretsManager {
+	return &SecretsManager{
+		client:      s.client,
+		forceDelete: true,
+	}
+}
+
 // Get retrieves the secret from AWS Secrets Manager for the given identifier, either an ARN or the
-// configured name of the desired secret.
+// configured name of the desired secret. A version stage or version ID may be selected by appending
+// "@STAGE" or "#VERSION_ID" to the identifier, e.g. "name@AWSPREVIOUS". Since names may contain "@",
+// a suffix containing "/" or "." is taken as part of the name, e.g. "svc/ops@example.com". Names
+// with any other "@" suffix may be given with an explicit stage, e.g. "svc/ops@admin@AWSCURRENT".
 func (s *SecretsManager) Get(id string) (map[string]interface{}, error) {
-	out, err := s.client.GetSecretValue(&secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(id),
-	})
+	m, _, err := s.GetVersion(id)
+	return m, err
+}
+
+// GetVersion behaves like Get but additionally returns the version of the secret retrieved.
+func (s *SecretsManager) GetVersion(id string) (map[string]interface{}, Version, error) {
+	out, err := s.client.GetSecretValue(input(id))
+	if err != nil {
+		return nil, Version{}, wrap(id, err)
+	}
+
+	version := Version{
+		ID:      aws.StringValue(out.VersionId),
+		Stages:  aws.StringValueSlice(out.VersionStages),
+		Created: aws.TimeValue(out.CreatedDate),
+	}
+
+	return parse(out.SecretString, out.SecretBinary), version, nil
+}
+
+// GetMetadata implements envsecret.MetadataStore, describing the version of the secret retrieved.
+func (s *SecretsManager) GetMetadata(id string) (map[string]interface{}, envsecret.Metadata, error) {
+	m, version, err := s.GetVersion(id)
+	if err != nil {
+		return nil, envsecret.Metadata{}, err
+	}
+
+	return m, describe(id, version), nil
+}
+
+// GetMany implements envsecret.BatchStore, retrieving as many secrets in each request as the API
+// allows. Identifiers selecting a version stage or ID, given by partial ARN, or in a request that
+// fails as a whole are retrieved individually. The result is keyed by the identifiers as given,
+// and secrets that could not be retrieved are reported with an envsecret.BatchError.
+func (s *SecretsManager) GetMany(ids []string) (map[string]map[string]interface{}, error) {
+	result, _, err := s.GetManyMetadata(ids)
+	return result, err
+}
+
+// GetManyMetadata implements envsecret.BatchMetadataStore, behaving as GetMany and additionally
+// describing the version of each secret retrieved.
+func (s *SecretsManager) GetManyMetadata(ids []string) (map[string]map[string]interface{}, map[string]envsecret.Metadata, error) {
+	return awssecret.GetMany(ids, s.getBatch, s.GetMetadata)
+}
+
+// getBatch retrieves a single batch of secrets, following pagination, into result, metadata and errs.
+func (s *SecretsManager) getBatch(ids []string, result map[string]map[string]interface{}, metadata map[string]envsecret.Metadata, errs envsecret.BatchError) error {
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	input := &secretsmanager.BatchGetSecretValueInput{
+		SecretIdList: aws.StringSlice(ids),
+	}
+	for {
+		out, err := s.client.BatchGetSecretValue(input)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range out.SecretValues {
+			m := parse(v.SecretString, v.SecretBinary)
+			version := Version{
+				ID:      aws.StringValue(v.VersionId),
+				Stages:  aws.StringValueSlice(v.VersionStages),
+				Created: aws.TimeValue(v.CreatedDate),
+			}
+			for _, key := range []string{aws.StringValue(v.Name), aws.StringValue(v.ARN)} {
+				if requested[key] {
+					result[key], metadata[key] = m, describe(key, version)
+				}
+			}
+		}
+
+		for _, e := range out.Errors {
+			id := aws.StringValue(e.SecretId)
+			if aws.StringValue(e.ErrorCode) == secretsmanager.ErrCodeResourceNotFoundException {
+				errs[id] = fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+			} else {
+				errs[id] = fmt.Errorf("%s: %s", aws.StringValue(e.ErrorCode), aws.StringValue(e.Message))
+			}
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// Put implements envsecret.WritableStore, storing the secret as a JSON string in a new version
+// of the secret with the given name, which is created should it not exist.
+func (s *SecretsManager) Put(id string, secret map[string]interface{}) error {
+	if awssecret.Parse(id).SelectsVersion() {
+		return fmt.Errorf("cannot put version %s, identifiers must name a secret", id)
+	}
+
+	b, err := json.Marshal(secret)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	_, err = s.client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(id),
+		SecretString: aws.String(string(b)),
+	})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != secretsmanager.ErrCodeResourceNotFoundException {
+		return err
+	}
+
+	_, err = s.client.CreateSecret(&secretsmanager.CreateSecretInput{
+		Name:         aws.String(id),
+		SecretString: aws.String(string(b)),
+	})
+	return err
+}
+
+// Delete implements envsecret.WritableStore, scheduling the secret for deletion after the default
+// recovery window, during which it may be restored but not recreated. See WithForceDelete.
+func (s *SecretsManager) Delete(id string) error {
+	input := &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(id),
+	}
+	if s.forceDelete {
+		input.ForceDeleteWithoutRecovery = aws.Bool(true)
+	}
+
+	_, err := s.client.DeleteSecret(input)
+	return wrap(id, err)
+}
+
+// List implements envsecret.ListStore, returning the name of every secret beginning with the
+// given prefix.
+func (s *SecretsManager) List(prefix string) ([]string, error) {
+	input := &secretsmanager.ListSecretsInput{}
+	if prefix != "" {
+		input.Filters = []*secretsmanager.Filter{{
+			Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+			Values: aws.StringSlice([]string{prefix}),
+		}}
 	}
 
-	var m map[string]interface{}
-	if err := json.Unmarshal([]byte(*out.SecretString), &m); err != nil {
-		return nil, err
+	var names []string
+	for {
+		out, err := s.client.ListSecrets(input)
+		if err != nil {
+			return nil, err
+		}
+
+		// The name filter is case insensitive, so match the prefix exactly here.
+		for _, entry := range out.SecretList {
+			if name := aws.StringValue(entry.Name); strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
 	}
+	sort.Strings(names)
+
+	return names, nil
+}
 
-	return m, nil
+// describe the version retrieved for the identifier.
+func describe(id string, version Version) envsecret.Metadata {
+	return awssecret.Describe(id, version.ID, version.Stages, version.Created)
+}
+
+// input builds the request for the given identifier, splitting off any version stage or ID.
+func input(id string) *secretsmanager.GetSecretValueInput {
+	parsed := awssecret.Parse(id)
+
+	in := &secretsmanager.GetSecretValueInput{SecretId: aws.String(parsed.Secret)}
+	if parsed.VersionID != "" {
+		in.VersionId = aws.String(parsed.VersionID)
+	}
+	if parsed.Stage != "" {
+		in.VersionStage = aws.String(parsed.Stage)
+	}
+
+	return in
+}
+
+// parse the secret's string or binary value.
+func parse(str *string, bin []byte) map[string]interface{} {
+	if str != nil {
+		return secretvalue.Parse([]byte(*str))
+	}
+	return secretvalue.Parse(bin)
+}
+
+// wrap translates missing secret errors into envsecret.ErrNotFound.
+func wrap(id string, err error) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return err
 }
 
 type awsSecretsManager interface {
 	GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error)
+	BatchGetSecretValue(input *secretsmanager.BatchGetSecretValueInput) (*secretsmanager.BatchGetSecretValueOutput, error)
+	PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error)
+	CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error)
+	DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error)
+	ListSecrets(input *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error)
 }
@@ -1,16 +1,25 @@
 package secretsmanager_test
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/gavincabbage/envsecret"
 	secretstore "github.com/gavincabbage/envsecret/store/secretsmanager"
+	"github.com/gavincabbage/envsecret/store/storetest"
 )
 
 const (
@@ -64,5 +73,435 @@ func TestSecretsManager_Get(t *testing.T) {
 			assert.Equal(t, test.expected, actual)
 		})
 	}
+}
+
+func TestSecretsManager_GetVersion(t *testing.T) {
+	created := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"json@AWSCURRENT": {
+				SecretString:  aws.String("{\"key\":\"value\"}"),
+				VersionId:     aws.String("v2"),
+				VersionStages: aws.StringSlice([]string{"AWSCURRENT"}),
+				CreatedDate:   aws.Time(created),
+			},
+			"json@AWSPREVIOUS": {
+				SecretString:  aws.String("{\"key\":\"old value\"}"),
+				VersionId:     aws.String("v1"),
+				VersionStages: aws.StringSlice([]string{"AWSPREVIOUS"}),
+			},
+			"json#v1": {
+				SecretString: aws.String("{\"key\":\"old value\"}"),
+				VersionId:    aws.String("v1"),
+			},
+			"plain@AWSCURRENT": {
+				SecretString: aws.String("plain value"),
+			},
+			"binary@AWSCURRENT": {
+				SecretBinary: []byte("{\"key\":\"binary value\"}"),
+			},
+			"raw@AWSCURRENT": {
+				SecretBinary: []byte("raw bytes"),
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		version  secretstore.Version
+		err      error
+	}{
+		{
+			name:     "json string",
+			id:       "json",
+			expected: map[string]interface{}{"key": "value"},
+			version:  secretstore.Version{ID: "v2", Stages: []string{"AWSCURRENT"}, Created: created},
+		},
+		{
+			name:     "version stage",
+			id:       "json@AWSPREVIOUS",
+			expected: map[string]interface{}{"key": "old value"},
+			version:  secretstore.Version{ID: "v1", Stages: []string{"AWSPREVIOUS"}},
+		},
+		{
+			name:     "version id",
+			id:       "json#v1",
+			expected: map[string]interface{}{"key": "old value"},
+			version:  secretstore.Version{ID: "v1", Stages: []string{}},
+		},
+		{
+			name:     "plain string",
+			id:       "plain",
+			expected: map[string]interface{}{"value": "plain value"},
+			version:  secretstore.Version{Stages: []string{}},
+		},
+		{
+			name:     "json binary",
+			id:       "binary",
+			expected: map[string]interface{}{"key": "binary value"},
+			version:  secretstore.Version{Stages: []string{}},
+		},
+		{
+			name:     "raw binary",
+			id:       "raw",
+			expected: map[string]interface{}{"value": "raw bytes"},
+			version:  secretstore.Version{Stages: []string{}},
+		},
+		{
+			name: "missing",
+			id:   "missing",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client)
+
+			actual, version, err := subject.GetVersion(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.version, version)
+		})
+	}
+}
+
+func TestSecretsManager_GetMetadata(t *testing.T) {
+	created := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"json@AWSCURRENT": {
+				Name:          aws.String("json"),
+				SecretString:  aws.String("{\"key\":\"value\"}"),
+				VersionId:     aws.String("v2"),
+				VersionStages: aws.StringSlice([]string{"AWSCURRENT", "AWSPENDING"}),
+				CreatedDate:   aws.Time(created),
+			},
+			"json@AWSPREVIOUS": {
+				Name:          aws.String("json"),
+				SecretString:  aws.String("{\"key\":\"old value\"}"),
+				VersionId:     aws.String("v1"),
+				VersionStages: aws.StringSlice([]string{"AWSPREVIOUS"}),
+			},
+		},
+	}
+	subject := secretstore.New(client)
+
+	_, metadata, err := subject.GetMetadata("json@AWSPREVIOUS")
+	assert.NoError(t, err)
+	assert.Equal(t, envsecret.Metadata{Version: "v1", Stage: "AWSPREVIOUS"}, metadata)
+
+	_, _, err = subject.GetMetadata("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+
+	spec := struct {
+		Current  envsecret.String `secret_keys:"key"`
+		Previous envsecret.String `secret_keys:"key"`
+	}{
+		Current:  envsecret.NewString("json"),
+		Previous: envsecret.NewString("json@AWSPREVIOUS"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, "v2", spec.Current.Version())
+	assert.Equal(t, envsecret.Metadata{Version: "v2", Stage: "AWSCURRENT", Created: created}, spec.Current.Metadata())
+	assert.Equal(t, "old value", spec.Previous.Value)
+	assert.Equal(t, "v1", spec.Previous.Version())
+}
+
+func TestSecretsManager_GetMany(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"previous@AWSPREVIOUS": {SecretString: aws.String("old value")},
+		},
+	}
+
+	var ids []string
+	for i := 0; i < 45; i++ {
+		id := fmt.Sprintf("secret-%d", i)
+		client.Secrets[id+"@AWSCURRENT"] = &secretsmanager.GetSecretValueOutput{
+			Name:         aws.String(id),
+			SecretString: aws.String(fmt.Sprintf("{\"key\":\"%d\"}", i)),
+		}
+		ids = append(ids, id)
+	}
+
+	subject := secretstore.New(client)
+
+	actual, err := subject.GetMany(append(ids, "previous@AWSPREVIOUS"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.BatchCount)
+	assert.Len(t, actual, 46)
+	assert.Equal(t, map[string]interface{}{"key": "44"}, actual["secret-44"])
+	assert.Equal(t, map[string]interface{}{"value": "old value"}, actual["previous@AWSPREVIOUS"])
+
+	actual, err = subject.GetMany([]string{"secret-1", "missing"})
+	assert.Equal(t, map[string]interface{}{"key": "1"}, actual["secret-1"])
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.Len(t, err.(envsecret.BatchError), 1)
+		assert.True(t, errors.Is(err.(envsecret.BatchError)["missing"], envsecret.ErrNotFound))
+	}
+}
+
+func TestSecretsManager_PutDelete(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{},
+	}
+	subject := secretstore.New(client)
+
+	assert.NoError(t, subject.Put("db", map[string]interface{}{"username": "user", "password": "old"}))
+	assert.NoError(t, subject.Put("db", map[string]interface{}{"username": "user", "password": "new"}))
+	assert.Equal(t, 1, client.CreateCount)
+
+	actual, err := subject.Get("db")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"username": "user", "password": "new"}, actual)
+
+	assert.Error(t, subject.Put("db@AWSPREVIOUS", map[string]interface{}{"value": "x"}))
+
+	assert.NoError(t, subject.Delete("db"))
+	assert.Nil(t, client.LastDelete.ForceDeleteWithoutRecovery)
+	_, err = subject.Get("db")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.True(t, errors.Is(subject.Delete("db"), envsecret.ErrNotFound))
+
+	assert.NoError(t, subject.Put("db", map[string]interface{}{"value": "x"}))
+	assert.NoError(t, subject.WithForceDelete().Delete("db"))
+	assert.True(t, aws.BoolValue(client.LastDelete.ForceDeleteWithoutRecovery))
+}
 
+func TestSecretsManager_List(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"tenants/a@AWSCURRENT": {Name: aws.String("tenants/a"), SecretString: aws.String("{\"key\":\"key-a\"}")},
+			"tenants/b@AWSCURRENT": {Name: aws.String("tenants/b"), SecretString: aws.String("key-b")},
+			"Tenants/c@AWSCURRENT": {Name: aws.String("Tenants/c"), SecretString: aws.String("key-c")},
+			"other@AWSCURRENT":     {Name: aws.String("other"), SecretString: aws.String("other")},
+		},
+	}
+	subject := secretstore.New(client)
+
+	ids, err := subject.List("tenants/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenants/a", "tenants/b"}, ids)
+
+	ids, err = subject.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Tenants/c", "other", "tenants/a", "tenants/b"}, ids)
+
+	spec := struct {
+		Tenants envsecret.Prefix
+	}{
+		Tenants: envsecret.NewPrefix("tenants/"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"tenants/a": {"key": "key-a"},
+		"tenants/b": {"value": "key-b"},
+	}, spec.Tenants.Values)
+	assert.Equal(t, 1, client.BatchCount)
+}
+
+func TestSecretsManager_GetManyFallback(t *testing.T) {
+	const partialARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"api-key@AWSCURRENT": {Name: aws.String("api-key"), SecretString: aws.String("abc123")},
+			partialARN + "@AWSCURRENT": {
+				Name:         aws.String("db"),
+				ARN:          aws.String(partialARN + "-AbCdEf"),
+				SecretString: aws.String("{\"username\":\"user\"}"),
+			},
+		},
+	}
+	subject := secretstore.New(client)
+
+	actual, err := subject.GetMany([]string{"api-key", partialARN})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, map[string]interface{}{"username": "user"}, actual[partialARN])
+
+	client.BatchErr = awserr.New("AccessDeniedException", "not authorized to perform secretsmanager:BatchGetSecretValue", nil)
+	actual, err = subject.GetMany([]string{"api-key", partialARN, "missing"})
+	assert.Equal(t, map[string]interface{}{"value": "abc123"}, actual["api-key"])
+	assert.Equal(t, map[string]interface{}{"username": "user"}, actual[partialARN])
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.Len(t, err.(envsecret.BatchError), 1)
+		assert.True(t, errors.Is(err.(envsecret.BatchError)["missing"], envsecret.ErrNotFound))
+	}
+}
+
+func TestSecretsManager_GetNameWithAt(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"svc/ops@example.com@AWSCURRENT": {Name: aws.String("svc/ops@example.com"), SecretString: aws.String("email")},
+			"svc/ops@admin@AWSCURRENT":       {Name: aws.String("svc/ops@admin"), SecretString: aws.String("admin")},
+			"svc/ops@AWSPREVIOUS":            {Name: aws.String("svc/ops"), SecretString: aws.String("previous")},
+		},
+	}
+	subject := secretstore.New(client)
+
+	cases := []struct {
+		id       string
+		expected string
+	}{
+		{id: "svc/ops@example.com", expected: "email"},
+		{id: "svc/ops@admin@AWSCURRENT", expected: "admin"},
+		{id: "svc/ops@AWSPREVIOUS", expected: "previous"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.id, func(t *testing.T) {
+			actual, err := subject.Get(test.id)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"value": test.expected}, actual)
+		})
+	}
+
+	actual, err := subject.GetMany([]string{"svc/ops@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, map[string]interface{}{"value": "email"}, actual["svc/ops@example.com"])
+}
+
+func TestSecretsManager_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		client := &fakeSecretsManager{Secrets: make(map[string]*secretsmanager.GetSecretValueOutput, len(fixtures))}
+		for id, secret := range fixtures {
+			client.Secrets[id+"@AWSCURRENT"] = &secretsmanager.GetSecretValueOutput{
+				Name:         aws.String(id),
+				SecretString: aws.String(string(storetest.Encode(secret))),
+			}
+		}
+		return secretstore.New(client)
+	}, storetest.IDs(func(name string) string {
+		return "app/" + name
+	}))
+}
+
+type fakeSecretsManager struct {
+	Secrets     map[string]*secretsmanager.GetSecretValueOutput
+	BatchErr    error
+	BatchCount  int
+	CreateCount int
+	LastDelete  *secretsmanager.DeleteSecretInput
+}
+
+func (f *fakeSecretsManager) BatchGetSecretValue(input *secretsmanager.BatchGetSecretValueInput) (*secretsmanager.BatchGetSecretValueOutput, error) {
+	f.BatchCount++
+	if f.BatchErr != nil {
+		return nil, f.BatchErr
+	} else if len(input.SecretIdList) > 20 {
+		return nil, errors.New("too many secret ids")
+	}
+
+	out := &secretsmanager.BatchGetSecretValueOutput{}
+	for _, id := range input.SecretIdList {
+		v, found := f.Secrets[aws.StringValue(id)+"@AWSCURRENT"]
+		if !found {
+			out.Errors = append(out.Errors, &secretsmanager.APIErrorType{
+				SecretId:  id,
+				ErrorCode: aws.String(secretsmanager.ErrCodeResourceNotFoundException),
+			})
+			continue
+		}
+		out.SecretValues = append(out.SecretValues, &secretsmanager.SecretValueEntry{
+			Name:          v.Name,
+			ARN:           v.ARN,
+			SecretString:  v.SecretString,
+			VersionId:     v.VersionId,
+			VersionStages: v.VersionStages,
+			CreatedDate:   v.CreatedDate,
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeSecretsManager) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	key := aws.StringValue(input.SecretId)
+	if input.VersionId != nil {
+		key += "#" + aws.StringValue(input.VersionId)
+	} else {
+		key += "@" + aws.StringValue(input.VersionStage)
+		if input.VersionStage == nil {
+			key += "AWSCURRENT"
+		}
+	}
+
+	out, found := f.Secrets[key]
+	if !found {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+
+	return out, nil
+}
+
+func (f *fakeSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	key := aws.StringValue(input.SecretId) + "@AWSCURRENT"
+	if _, found := f.Secrets[key]; !found {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+
+	f.Secrets[key] = &secretsmanager.GetSecretValueOutput{Name: input.SecretId, SecretString: input.SecretString}
+	return &secretsmanager.PutSecretValueOutput{}, nil
+}
+
+func (f *fakeSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	f.CreateCount++
+	key := aws.StringValue(input.Name) + "@AWSCURRENT"
+	if _, found := f.Secrets[key]; found {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "exists", nil)
+	}
+
+	f.Secrets[key] = &secretsmanager.GetSecretValueOutput{Name: input.Name, SecretString: input.SecretString}
+	return &secretsmanager.CreateSecretOutput{}, nil
+}
+
+func (f *fakeSecretsManager) DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	f.LastDelete = input
+	key := aws.StringValue(input.SecretId) + "@AWSCURRENT"
+	if _, found := f.Secrets[key]; !found {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+
+	delete(f.Secrets, key)
+	return &secretsmanager.DeleteSecretOutput{}, nil
+}
+
+// ListSecrets returns one secret per page, matching the name filter case insensitively as AWS does.
+func (f *fakeSecretsManager) ListSecrets(input *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+	var names []string
+	for key, v := range f.Secrets {
+		if !strings.HasSuffix(key, "@AWSCURRENT") {
+			continue
+		}
+		name := aws.StringValue(v.Name)
+		if len(input.Filters) == 0 || strings.HasPrefix(strings.ToLower(name), strings.ToLower(aws.StringValue(input.Filters[0].Values[0]))) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start := 0
+	if input.NextToken != nil {
+		start, _ = strconv.Atoi(aws.StringValue(input.NextToken))
+	}
+	if start >= len(names) {
+		return &secretsmanager.ListSecretsOutput{}, nil
+	}
+
+	out := &secretsmanager.ListSecretsOutput{
+		SecretList: []*secretsmanager.SecretListEntry{{Name: aws.String(names[start])}},
+	}
+	if start+1 < len(names) {
+		out.NextToken = aws.String(strconv.Itoa(start + 1))
+	}
+	return out, nil
 }
@@ -0,0 +1,184 @@
+package secretsmanagerv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/awssecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// SecretsManager provides access to AWS Secrets Manager using the AWS SDK for Go v2.
+type SecretsManager struct {
+	client awsSecretsManager
+	ctx    context.Context
+}
+
+// Version describes the version of a secret returned by AWS Secrets Manager.
+type Version struct {
+	ID      string
+	Stages  []string
+	Created time.Time
+}
+
+// New returns a SecretsManager instance configured to use the given AWS Secrets Manager client.
+func New(client awsSecretsManager) *SecretsManager {
+	return &SecretsManager{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext returns a copy of the SecretsManager which passes the given context to every request.
+func (s *SecretsManager) WithContext(ctx context.Context) *SecretsManager {
+	return &SecretsManager{
+		client: s.client,
+		ctx:    ctx,
+	}
+}
+
+// Get retrieves the secret from AWS Secrets Manager for the given identifier, either an ARN or the
+// configured name of the desired secret. A version stage or version ID may be selected by appending
+// "@STAGE" or "#VERSION_ID" to the identifier, e.g. "name@AWSPREVIOUS". Since names may contain "@",
+// a suffix containing "/" or "." is taken as part of the name, e.g. "svc/ops@example.com". Names
+// with any other "@" suffix may be given with an explicit stage, e.g. "svc/ops@admin@AWSCURRENT".
+func (s *SecretsManager) Get(id string) (map[string]interface{}, error) {
+	m, _, err := s.GetVersion(id)
+	return m, err
+}
+
+// GetVersion behaves like Get but additionally returns the version of the secret retrieved.
+func (s *SecretsManager) GetVersion(id string) (map[string]interface{}, Version, error) {
+	out, err := s.client.GetSecretValue(s.ctx, input(id))
+	if err != nil {
+		return nil, Version{}, wrap(id, err)
+	}
+
+	version := Version{
+		ID:      aws.ToString(out.VersionId),
+		Stages:  out.VersionStages,
+		Created: aws.ToTime(out.CreatedDate),
+	}
+
+	return parse(out.SecretString, out.SecretBinary), version, nil
+}
+
+// GetMetadata implements envsecret.MetadataStore, describing the version of the secret retrieved.
+func (s *SecretsManager) GetMetadata(id string) (map[string]interface{}, envsecret.Metadata, error) {
+	m, version, err := s.GetVersion(id)
+	if err != nil {
+		return nil, envsecret.Metadata{}, err
+	}
+
+	return m, describe(id, version), nil
+}
+
+// GetMany implements envsecret.BatchStore, retrieving as many secrets in each request as the API
+// allows. Identifiers selecting a version stage or ID, given by partial ARN, or in a request that
+// fails as a whole are retrieved individually. The result is keyed by the identifiers as given,
+// and secrets that could not be retrieved are reported with an envsecret.BatchError.
+func (s *SecretsManager) GetMany(ids []string) (map[string]map[string]interface{}, error) {
+	result, _, err := s.GetManyMetadata(ids)
+	return result, err
+}
+
+// GetManyMetadata implements envsecret.BatchMetadataStore, behaving as GetMany and additionally
+// describing the version of each secret retrieved.
+func (s *SecretsManager) GetManyMetadata(ids []string) (map[string]map[string]interface{}, map[string]envsecret.Metadata, error) {
+	return awssecret.GetMany(ids, s.getBatch, s.GetMetadata)
+}
+
+// getBatch retrieves a single batch of secrets, following pagination, into result, metadata and errs.
+func (s *SecretsManager) getBatch(ids []string, result map[string]map[string]interface{}, metadata map[string]envsecret.Metadata, errs envsecret.BatchError) error {
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	input := &secretsmanager.BatchGetSecretValueInput{
+		SecretIdList: ids,
+	}
+	for {
+		out, err := s.client.BatchGetSecretValue(s.ctx, input)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range out.SecretValues {
+			m := parse(v.SecretString, v.SecretBinary)
+			version := Version{
+				ID:      aws.ToString(v.VersionId),
+				Stages:  v.VersionStages,
+				Created: aws.ToTime(v.CreatedDate),
+			}
+			for _, key := range []string{aws.ToString(v.Name), aws.ToString(v.ARN)} {
+				if requested[key] {
+					result[key], metadata[key] = m, describe(key, version)
+				}
+			}
+		}
+
+		for _, e := range out.Errors {
+			id := aws.ToString(e.SecretId)
+			if aws.ToString(e.ErrorCode) == "ResourceNotFoundException" {
+				errs[id] = fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+			} else {
+				errs[id] = fmt.Errorf("%s: %s", aws.ToString(e.ErrorCode), aws.ToString(e.Message))
+			}
+		}
+
+		if aws.ToString(out.NextToken) == "" {
+			return nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// describe the version retrieved for the identifier.
+func describe(id string, version Version) envsecret.Metadata {
+	return awssecret.Describe(id, version.ID, version.Stages, version.Created)
+}
+
+// input builds the request for the given identifier, splitting off any version stage or ID.
+func input(id string) *secretsmanager.GetSecretValueInput {
+	parsed := awssecret.Parse(id)
+
+	in := &secretsmanager.GetSecretValueInput{SecretId: aws.String(parsed.Secret)}
+	if parsed.VersionID != "" {
+		in.VersionId = aws.String(parsed.VersionID)
+	}
+	if parsed.Stage != "" {
+		in.VersionStage = aws.String(parsed.Stage)
+	}
+
+	return in
+}
+
+// parse the secret's string or binary value.
+func parse(str *string, bin []byte) map[string]interface{} {
+	if str != nil {
+		return secretvalue.Parse([]byte(*str))
+	}
+	return secretvalue.Parse(bin)
+}
+
+// wrap translates missing secret errors into envsecret.ErrNotFound.
+func wrap(id string, err error) error {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return err
+}
+
+type awsSecretsManager interface {
+	GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	BatchGetSecretValue(ctx context.Context, input *secretsmanager.BatchGetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.BatchGetSecretValueOutput, error)
+}
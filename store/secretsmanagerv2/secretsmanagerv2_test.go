@@ -0,0 +1,340 @@
+package secretsmanagerv2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/secretsmanagerv2"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestSecretsManager_GetVersion(t *testing.T) {
+	created := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"json@AWSCURRENT": {
+				SecretString:  aws.String("{\"key\":\"value\"}"),
+				VersionId:     aws.String("v2"),
+				VersionStages: []string{"AWSCURRENT"},
+				CreatedDate:   aws.Time(created),
+			},
+			"json@AWSPREVIOUS": {
+				SecretString:  aws.String("{\"key\":\"old value\"}"),
+				VersionId:     aws.String("v1"),
+				VersionStages: []string{"AWSPREVIOUS"},
+			},
+			"json#v1": {
+				SecretString: aws.String("{\"key\":\"old value\"}"),
+				VersionId:    aws.String("v1"),
+			},
+			"plain@AWSCURRENT": {
+				SecretString: aws.String("plain value"),
+			},
+			"binary@AWSCURRENT": {
+				SecretBinary: []byte("{\"key\":\"binary value\"}"),
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		version  secretstore.Version
+		err      error
+	}{
+		{
+			name:     "json string",
+			id:       "json",
+			expected: map[string]interface{}{"key": "value"},
+			version:  secretstore.Version{ID: "v2", Stages: []string{"AWSCURRENT"}, Created: created},
+		},
+		{
+			name:     "version stage",
+			id:       "json@AWSPREVIOUS",
+			expected: map[string]interface{}{"key": "old value"},
+			version:  secretstore.Version{ID: "v1", Stages: []string{"AWSPREVIOUS"}},
+		},
+		{
+			name:     "version id",
+			id:       "json#v1",
+			expected: map[string]interface{}{"key": "old value"},
+			version:  secretstore.Version{ID: "v1"},
+		},
+		{
+			name:     "plain string",
+			id:       "plain",
+			expected: map[string]interface{}{"value": "plain value"},
+		},
+		{
+			name:     "json binary",
+			id:       "binary",
+			expected: map[string]interface{}{"key": "binary value"},
+		},
+		{
+			name: "missing",
+			id:   "missing",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client)
+
+			actual, version, err := subject.GetVersion(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.version, version)
+		})
+	}
+}
+
+func TestSecretsManager_WithContext(t *testing.T) {
+	client := &fakeSecretsManager{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	subject := secretstore.New(client).WithContext(ctx)
+
+	_, err := subject.Get("json")
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = subject.GetMany([]string{"json"})
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.Equal(t, context.Canceled, err.(envsecret.BatchError)["json"])
+	}
+}
+
+func TestSecretsManager_GetMetadata(t *testing.T) {
+	created := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"json@AWSCURRENT": {
+				Name:          aws.String("json"),
+				SecretString:  aws.String("{\"key\":\"value\"}"),
+				VersionId:     aws.String("v2"),
+				VersionStages: []string{"AWSCURRENT", "AWSPENDING"},
+				CreatedDate:   aws.Time(created),
+			},
+			"json@AWSPREVIOUS": {
+				Name:          aws.String("json"),
+				SecretString:  aws.String("{\"key\":\"old value\"}"),
+				VersionId:     aws.String("v1"),
+				VersionStages: []string{"AWSPREVIOUS"},
+			},
+		},
+	}
+	subject := secretstore.New(client)
+
+	_, metadata, err := subject.GetMetadata("json@AWSPREVIOUS")
+	assert.NoError(t, err)
+	assert.Equal(t, envsecret.Metadata{Version: "v1", Stage: "AWSPREVIOUS"}, metadata)
+
+	_, _, err = subject.GetMetadata("missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+
+	spec := struct {
+		Current  envsecret.String `secret_keys:"key"`
+		Previous envsecret.String `secret_keys:"key"`
+	}{
+		Current:  envsecret.NewString("json"),
+		Previous: envsecret.NewString("json@AWSPREVIOUS"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, "v2", spec.Current.Version())
+	assert.Equal(t, envsecret.Metadata{Version: "v2", Stage: "AWSCURRENT", Created: created}, spec.Current.Metadata())
+	assert.Equal(t, "old value", spec.Previous.Value)
+	assert.Equal(t, "v1", spec.Previous.Version())
+}
+
+func TestSecretsManager_GetMany(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"previous@AWSPREVIOUS": {SecretString: aws.String("old value")},
+		},
+	}
+
+	var ids []string
+	for i := 0; i < 45; i++ {
+		id := fmt.Sprintf("secret-%d", i)
+		client.Secrets[id+"@AWSCURRENT"] = &secretsmanager.GetSecretValueOutput{
+			Name:         aws.String(id),
+			SecretString: aws.String(fmt.Sprintf("{\"key\":\"%d\"}", i)),
+		}
+		ids = append(ids, id)
+	}
+
+	subject := secretstore.New(client)
+
+	actual, err := subject.GetMany(append(ids, "previous@AWSPREVIOUS"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.BatchCount)
+	assert.Len(t, actual, 46)
+	assert.Equal(t, map[string]interface{}{"key": "44"}, actual["secret-44"])
+	assert.Equal(t, map[string]interface{}{"value": "old value"}, actual["previous@AWSPREVIOUS"])
+
+	actual, err = subject.GetMany([]string{"secret-1", "missing"})
+	assert.Equal(t, map[string]interface{}{"key": "1"}, actual["secret-1"])
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.Len(t, err.(envsecret.BatchError), 1)
+		assert.True(t, errors.Is(err.(envsecret.BatchError)["missing"], envsecret.ErrNotFound))
+	}
+}
+
+func TestSecretsManager_GetManyFallback(t *testing.T) {
+	const partialARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"api-key@AWSCURRENT": {Name: aws.String("api-key"), SecretString: aws.String("abc123")},
+			partialARN + "@AWSCURRENT": {
+				Name:         aws.String("db"),
+				ARN:          aws.String(partialARN + "-AbCdEf"),
+				SecretString: aws.String("{\"username\":\"user\"}"),
+			},
+		},
+	}
+	subject := secretstore.New(client)
+
+	actual, err := subject.GetMany([]string{"api-key", partialARN})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, map[string]interface{}{"username": "user"}, actual[partialARN])
+
+	client.BatchErr = errors.New("AccessDeniedException: not authorized to perform secretsmanager:BatchGetSecretValue")
+	actual, err = subject.GetMany([]string{"api-key", partialARN, "missing"})
+	assert.Equal(t, map[string]interface{}{"value": "abc123"}, actual["api-key"])
+	assert.Equal(t, map[string]interface{}{"username": "user"}, actual[partialARN])
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.Len(t, err.(envsecret.BatchError), 1)
+		assert.True(t, errors.Is(err.(envsecret.BatchError)["missing"], envsecret.ErrNotFound))
+	}
+}
+
+func TestSecretsManager_GetNameWithAt(t *testing.T) {
+	client := &fakeSecretsManager{
+		Secrets: map[string]*secretsmanager.GetSecretValueOutput{
+			"svc/ops@example.com@AWSCURRENT": {Name: aws.String("svc/ops@example.com"), SecretString: aws.String("email")},
+			"svc/ops@admin@AWSCURRENT":       {Name: aws.String("svc/ops@admin"), SecretString: aws.String("admin")},
+			"svc/ops@AWSPREVIOUS":            {Name: aws.String("svc/ops"), SecretString: aws.String("previous")},
+		},
+	}
+	subject := secretstore.New(client)
+
+	cases := []struct {
+		id       string
+		expected string
+	}{
+		{id: "svc/ops@example.com", expected: "email"},
+		{id: "svc/ops@admin@AWSCURRENT", expected: "admin"},
+		{id: "svc/ops@AWSPREVIOUS", expected: "previous"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.id, func(t *testing.T) {
+			actual, err := subject.Get(test.id)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"value": test.expected}, actual)
+		})
+	}
+
+	actual, err := subject.GetMany([]string{"svc/ops@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.BatchCount)
+	assert.Equal(t, map[string]interface{}{"value": "email"}, actual["svc/ops@example.com"])
+}
+
+func TestSecretsManager_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		client := &fakeSecretsManager{Secrets: make(map[string]*secretsmanager.GetSecretValueOutput, len(fixtures))}
+		for id, secret := range fixtures {
+			client.Secrets[id+"@AWSCURRENT"] = &secretsmanager.GetSecretValueOutput{
+				Name:         aws.String(id),
+				SecretString: aws.String(string(storetest.Encode(secret))),
+			}
+		}
+		return secretstore.New(client)
+	}, storetest.IDs(func(name string) string {
+		return "app/" + name
+	}), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.SecretsManager).WithContext(ctx)
+	}))
+}
+
+type fakeSecretsManager struct {
+	Secrets    map[string]*secretsmanager.GetSecretValueOutput
+	BatchErr   error
+	BatchCount int
+}
+
+func (f *fakeSecretsManager) GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key := aws.ToString(input.SecretId)
+	if input.VersionId != nil {
+		key += "#" + aws.ToString(input.VersionId)
+	} else {
+		key += "@" + aws.ToString(input.VersionStage)
+		if input.VersionStage == nil {
+			key += "AWSCURRENT"
+		}
+	}
+
+	out, found := f.Secrets[key]
+	if !found {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+
+	return out, nil
+}
+
+func (f *fakeSecretsManager) BatchGetSecretValue(ctx context.Context, input *secretsmanager.BatchGetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.BatchGetSecretValueOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.BatchCount++
+	if f.BatchErr != nil {
+		return nil, f.BatchErr
+	} else if len(input.SecretIdList) > 20 {
+		return nil, errors.New("too many secret ids")
+	}
+
+	out := &secretsmanager.BatchGetSecretValueOutput{}
+	for _, id := range input.SecretIdList {
+		v, found := f.Secrets[id+"@AWSCURRENT"]
+		if !found {
+			out.Errors = append(out.Errors, types.APIErrorType{
+				SecretId:  aws.String(id),
+				ErrorCode: aws.String("ResourceNotFoundException"),
+			})
+			continue
+		}
+		out.SecretValues = append(out.SecretValues, types.SecretValueEntry{
+			Name:          v.Name,
+			ARN:           v.ARN,
+			SecretString:  v.SecretString,
+			VersionId:     v.VersionId,
+			VersionStages: v.VersionStages,
+			CreatedDate:   v.CreatedDate,
+		})
+	}
+
+	return out, nil
+}
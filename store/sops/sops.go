@@ -0,0 +1,136 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// DefaultCommand is the sops command run by a SOPS store returned by New.
+const DefaultCommand = "sops"
+
+// SOPS reads secrets from files encrypted with SOPS by running the sops command line tool, so that
+// its many key services are not linked into every program using envsecret.
+//
+// Decryption keys are located by sops itself, e.g. age identities from the file named by
+// SOPS_AGE_KEY_FILE and PGP keys from the local GnuPG keyring.
+type SOPS struct {
+	command string
+	ctx     context.Context
+}
+
+// New returns a SOPS store running the sops command found in PATH.
+func New() *SOPS {
+	return NewWithCommand(DefaultCommand)
+}
+
+// NewWithCommand returns a SOPS store running the given sops command.
+func NewWithCommand(command string) *SOPS {
+	return &SOPS{
+		command: command,
+		ctx:     context.Background(),
+	}
+}
+
+// WithContext returns a copy of the SOPS store which runs sops with the given context.
+func (s *SOPS) WithContext(ctx context.Context) *SOPS {
+	return &SOPS{
+		command: s.command,
+		ctx:     ctx,
+	}
+}
+
+// Get decrypts the file identified by "path/to/file.enc.yaml[#dotted.key.path]" and returns the
+// selected subtree, or the whole document if no key path is given. JSON, YAML and dotenv files are
+// recognized by their extension, and any other file is parsed as JSON or returned as a single value.
+// A subtree which is not an object is returned as a single value map.
+func (s *SOPS) Get(id string) (map[string]interface{}, error) {
+	path, keyPath := id, ""
+	if i := strings.LastIndex(id, "#"); i >= 0 {
+		path, keyPath = id[:i], id[i+1:]
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	cleartext, err := s.decrypt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(cleartext, &tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(cleartext, &tree)
+	case ".env":
+		tree, err = secretvalue.ParseEnv(cleartext)
+	default:
+		tree = secretvalue.Parse(cleartext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if keyPath == "" {
+		return tree, nil
+	}
+
+	return selectPath(tree, keyPath, id)
+}
+
+// decrypt the file at path with sops.
+func (s *SOPS) decrypt(path string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(s.ctx, s.command, "--decrypt", "--", path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if s.ctx.Err() != nil {
+			err = s.ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("decrypting %s: %w: %s", path, err, msg)
+		}
+		return nil, fmt.Errorf("decrypting %s: %w", path, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// selectPath walks the dotted key path through nested objects in the tree.
+func selectPath(tree map[string]interface{}, keyPath, id string) (map[string]interface{}, error) {
+	var node interface{} = tree
+	for _, key := range strings.Split(keyPath, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+		}
+		if node, ok = m[key]; !ok {
+			return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+		}
+	}
+
+	if m, ok := node.(map[string]interface{}); ok {
+		return m, nil
+	}
+
+	return map[string]interface{}{
+		secretvalue.Key: node,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package sops_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/sops"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestSOPS_Get(t *testing.T) {
+	requireSOPS(t)
+
+	// testdata/keys.txt is an age identity generated solely for these fixtures.
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join("testdata", "keys.txt"))
+
+	tampered := filepath.Join(t.TempDir(), "tampered.enc.json")
+	b, err := os.ReadFile(filepath.Join("testdata", "secrets.enc.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tampered, []byte(`{"extra":"unencrypted",`+string(b[1:])), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name: "whole yaml document",
+			id:   "testdata/secrets.enc.yaml",
+			expected: map[string]interface{}{
+				"database": map[string]interface{}{
+					"username": "user",
+					"password": "pass",
+					"port":     5432,
+				},
+				"api_key": "abc123",
+			},
+		},
+		{
+			name: "yaml subtree",
+			id:   "testdata/secrets.enc.yaml#database",
+			expected: map[string]interface{}{
+				"username": "user",
+				"password": "pass",
+				"port":     5432,
+			},
+		},
+		{
+			name:     "yaml leaf",
+			id:       "testdata/secrets.enc.yaml#database.password",
+			expected: map[string]interface{}{"value": "pass"},
+		},
+		{
+			name: "json subtree",
+			id:   "testdata/secrets.enc.json#login",
+			expected: map[string]interface{}{
+				"username": "user",
+				"password": "pass",
+			},
+		},
+		{
+			name: "missing key path",
+			id:   "testdata/secrets.enc.yaml#database.host",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "key path through a leaf",
+			id:   "testdata/secrets.enc.yaml#api_key.nested",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "missing file",
+			id:   "testdata/missing.enc.yaml",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "tampered file",
+			id:   tampered,
+			err:  errors.New("decrypting"),
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New()
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.Error(t, err)
+				if !errors.Is(err, test.err) {
+					assert.Contains(t, fmt.Sprint(err), test.err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSOPS_Conformance(t *testing.T) {
+	requireSOPS(t)
+
+	// testdata/conformance.enc.json holds the storetest fixtures, keyed by name.
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join("testdata", "keys.txt"))
+
+	storetest.Run(t, func(t *testing.T, _ envsecret.Fixtures) envsecret.Store {
+		return secretstore.New()
+	}, storetest.IDs(func(name string) string {
+		return filepath.Join("testdata", "conformance.enc.json") + "#" + name
+	}), storetest.WithContext(func(store envsecret.Store, ctx context.Context) envsecret.Store {
+		return store.(*secretstore.SOPS).WithContext(ctx)
+	}))
+}
+
+func TestSOPS_Command(t *testing.T) {
+	_, err := secretstore.NewWithCommand(filepath.Join(t.TempDir(), "sops")).Get("testdata/secrets.enc.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "decrypting testdata/secrets.enc.json")
+	assert.False(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+// requireSOPS skips the test if the sops command is not installed, or fails it should
+// ENVSECRET_REQUIRE_SOPS be set, as it is in the test image.
+func requireSOPS(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath(secretstore.DefaultCommand); err != nil {
+		if os.Getenv("ENVSECRET_REQUIRE_SOPS") != "" {
+			t.Fatalf("sops is required: %v", err)
+		}
+		t.Skip("sops is not installed, set ENVSECRET_REQUIRE_SOPS to fail instead")
+	}
+}
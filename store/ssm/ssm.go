@@ -0,0 +1,202 @@
+package ssm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/internal/secretvalue"
+)
+
+// maxBatchSize is the maximum number of names accepted by a single GetParameters call.
+const maxBatchSize = 10
+
+// SSM provides access to AWS Systems Manager Parameter Store.
+type SSM struct {
+	client awsSSM
+}
+
+// New returns an SSM instance configured to use the given AWS Systems Manager client.
+func New(client awsSSM) *SSM {
+	return &SSM{
+		client: client,
+	}
+}
+
+// Get retrieves the parameter for the given identifier, either an ARN or the name of the
+// desired parameter, optionally followed by a version or label selector, e.g. "/app/db:3" or
+// "/app/db:prod". SecureString parameters are decrypted. An identifier ending in "/" is
+// treated as a path, and every parameter beneath it is returned keyed by its name relative
+// to the path.
+func (s *SSM) Get(id string) (map[string]interface{}, error) {
+	if strings.HasSuffix(id, "/") {
+		return s.getPath(id)
+	}
+
+	out, err := s.client.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(id),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, wrap(id, err)
+	}
+
+	return secretvalue.Parse([]byte(aws.StringValue(out.Parameter.Value))), nil
+}
+
+// GetMany implements envsecret.BatchStore, batching as many names into each request as the API
+// allows. Paths and identifiers with selectors are retrieved individually. The result is keyed by
+// the identifiers as given, and parameters that could not be retrieved are reported with an
+// envsecret.BatchError.
+func (s *SSM) GetMany(ids []string) (map[string]map[string]interface{}, error) {
+	var (
+		result = make(map[string]map[string]interface{}, len(ids))
+		errs   = make(envsecret.BatchError)
+		names  []string
+	)
+	for _, id := range ids {
+		if !strings.HasSuffix(id, "/") && !hasSelector(id) {
+			names = append(names, id)
+			continue
+		}
+
+		// Parameters are returned without their selectors, so those selecting a version or label
+		// could not be matched to their identifiers and are retrieved individually, like paths.
+		m, err := s.Get(id)
+		if err != nil {
+			errs[id] = err
+			continue
+		}
+		result[id] = m
+	}
+
+	for start := 0; start < len(names); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		out, err := s.client.GetParameters(&ssm.GetParametersInput{
+			Names:          aws.StringSlice(names[start:end]),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			for _, name := range names[start:end] {
+				errs[name] = err
+			}
+			continue
+		}
+
+		for _, p := range out.Parameters {
+			m := secretvalue.Parse([]byte(aws.StringValue(p.Value)))
+			result[aws.StringValue(p.Name)] = m
+			if p.ARN != nil {
+				result[aws.StringValue(p.ARN)] = m
+			}
+		}
+	}
+
+	for _, id := range names {
+		if _, found := result[id]; !found {
+			if _, failed := errs[id]; !failed {
+				errs[id] = fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, errs
+	}
+
+	return result, nil
+}
+
+// List implements envsecret.ListStore, returning the name of every parameter beneath the given
+// path, e.g. "/tenants/".
+func (s *SSM) List(prefix string) ([]string, error) {
+	path := strings.TrimSuffix(prefix, "/")
+	if path == "" {
+		path = "/"
+	}
+
+	var names []string
+	input := &ssm.GetParametersByPathInput{
+		Path:      aws.String(path),
+		Recursive: aws.Bool(true),
+	}
+	for {
+		out, err := s.client.GetParametersByPath(input)
+		if err != nil {
+			return nil, wrap(prefix, err)
+		}
+
+		for _, p := range out.Parameters {
+			names = append(names, aws.StringValue(p.Name))
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// getPath retrieves every parameter beneath the given path.
+func (s *SSM) getPath(path string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(strings.TrimSuffix(path, "/")),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+	for {
+		out, err := s.client.GetParametersByPath(input)
+		if err != nil {
+			return nil, wrap(path, err)
+		}
+
+		for _, p := range out.Parameters {
+			m[strings.TrimPrefix(aws.StringValue(p.Name), path)] = aws.StringValue(p.Value)
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	if len(m) == 0 {
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, path)
+	}
+
+	return m, nil
+}
+
+// hasSelector reports whether the identifier ends in a ":version" or ":label" selector, which
+// follows the last "/" of a name or ARN.
+func hasSelector(id string) bool {
+	return strings.LastIndex(id, ":") > strings.LastIndex(id, "/")
+}
+
+// wrap translates missing parameter errors into envsecret.ErrNotFound.
+func wrap(id string, err error) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+	}
+	return err
+}
+
+type awsSSM interface {
+	GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	GetParameters(input *ssm.GetParametersInput) (*ssm.GetParametersOutput, error)
+	GetParametersByPath(input *ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error)
+}
@@ -0,0 +1,215 @@
+package ssm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	secretstore "github.com/gavincabbage/envsecret/store/ssm"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestSSM_Get(t *testing.T) {
+	client := &fakeSSM{
+		Parameters: map[string]string{
+			"/app/json":        "{\"key\":\"value\"}",
+			"/app/plain":       "plain value",
+			"/app/db/username": "user",
+			"/app/db/password": "pass",
+		},
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name: "json parameter",
+			id:   "/app/json",
+			expected: map[string]interface{}{
+				"key": "value",
+			},
+		},
+		{
+			name: "plain parameter",
+			id:   "/app/plain",
+			expected: map[string]interface{}{
+				"value": "plain value",
+			},
+		},
+		{
+			name: "path",
+			id:   "/app/db/",
+			expected: map[string]interface{}{
+				"username": "user",
+				"password": "pass",
+			},
+		},
+		{
+			name: "missing parameter",
+			id:   "/app/missing",
+			err:  envsecret.ErrNotFound,
+		},
+		{
+			name: "missing path",
+			id:   "/nothing/",
+			err:  envsecret.ErrNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := secretstore.New(client)
+
+			actual, err := subject.Get(test.id)
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSSM_GetMany(t *testing.T) {
+	client := &fakeSSM{Parameters: make(map[string]string)}
+
+	var ids []string
+	for _, c := range "abcdefghijklmnopqrstuvwxy" {
+		id := "/app/" + string(c)
+		client.Parameters[id] = string(c)
+		ids = append(ids, id)
+	}
+
+	subject := secretstore.New(client)
+
+	actual, err := subject.GetMany(ids)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.GetParametersCount)
+	assert.Len(t, actual, len(ids))
+	assert.Equal(t, map[string]interface{}{"value": "a"}, actual["/app/a"])
+
+	client.Parameters["/app/db:3"] = "third"
+	client.Parameters["/app/db:prod"] = "labeled"
+	client.Parameters["arn:aws:ssm:us-east-1:123456789012:parameter/app/db"] = "latest"
+	actual, err = subject.GetMany([]string{"/app/db:3", "/app/db:prod", "arn:aws:ssm:us-east-1:123456789012:parameter/app/db"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"value": "third"}, actual["/app/db:3"])
+	assert.Equal(t, map[string]interface{}{"value": "labeled"}, actual["/app/db:prod"])
+	assert.Equal(t, map[string]interface{}{"value": "latest"}, actual["arn:aws:ssm:us-east-1:123456789012:parameter/app/db"])
+	assert.Equal(t, 4, client.GetParametersCount)
+
+	actual, err = subject.GetMany([]string{"/app/a", "/app/missing"})
+	assert.Equal(t, map[string]interface{}{"value": "a"}, actual["/app/a"])
+	if assert.IsType(t, envsecret.BatchError{}, err) {
+		assert.True(t, errors.Is(err.(envsecret.BatchError)["/app/missing"], envsecret.ErrNotFound))
+	}
+}
+
+func TestSSM_List(t *testing.T) {
+	client := &fakeSSM{
+		Parameters: map[string]string{
+			"/tenants/a":        "{\"key\":\"key-a\"}",
+			"/tenants/nested/b": "key-b",
+			"/other":            "other",
+		},
+	}
+	subject := secretstore.New(client)
+
+	ids, err := subject.List("/tenants/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/tenants/a", "/tenants/nested/b"}, ids)
+
+	ids, err = subject.List("/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/other", "/tenants/a", "/tenants/nested/b"}, ids)
+	assert.False(t, client.Decrypted)
+
+	spec := struct {
+		Tenants envsecret.Prefix
+	}{
+		Tenants: envsecret.NewPrefix("/tenants"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"/tenants/a":        {"key": "key-a"},
+		"/tenants/nested/b": {"value": "key-b"},
+	}, spec.Tenants.Values)
+	assert.Equal(t, 1, client.GetParametersCount)
+}
+
+func TestSSM_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		client := &fakeSSM{Parameters: make(map[string]string, len(fixtures))}
+		for id, secret := range fixtures {
+			client.Parameters[id] = string(storetest.Encode(secret))
+		}
+		return secretstore.New(client)
+	}, storetest.IDs(func(name string) string {
+		return "/app/" + name
+	}))
+}
+
+type fakeSSM struct {
+	Parameters         map[string]string
+	GetParametersCount int
+	Decrypted          bool
+}
+
+func (f *fakeSSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	v, found := f.Parameters[aws.StringValue(input.Name)]
+	if !found {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil)
+	}
+
+	return &ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{Name: input.Name, Value: aws.String(v)},
+	}, nil
+}
+
+func (f *fakeSSM) GetParameters(input *ssm.GetParametersInput) (*ssm.GetParametersOutput, error) {
+	f.GetParametersCount++
+	if len(input.Names) > 10 {
+		return nil, errors.New("too many names")
+	}
+
+	out := &ssm.GetParametersOutput{}
+	for _, name := range input.Names {
+		v, found := f.Parameters[aws.StringValue(name)]
+		if !found {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+			continue
+		}
+
+		// Like the API, return the name without any selector.
+		p := &ssm.Parameter{Name: name, Value: aws.String(v)}
+		if i := strings.LastIndex(*name, ":"); i > strings.LastIndex(*name, "/") {
+			p.Name, p.Selector = aws.String((*name)[:i]), aws.String((*name)[i:])
+		}
+		out.Parameters = append(out.Parameters, p)
+	}
+
+	return out, nil
+}
+
+func (f *fakeSSM) GetParametersByPath(input *ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error) {
+	f.Decrypted = f.Decrypted || aws.BoolValue(input.WithDecryption)
+
+	out := &ssm.GetParametersByPathOutput{}
+	for name, v := range f.Parameters {
+		if strings.HasPrefix(name, strings.TrimSuffix(aws.StringValue(input.Path), "/")+"/") {
+			out.Parameters = append(out.Parameters, &ssm.Parameter{Name: aws.String(name), Value: aws.String(v)})
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,303 @@
+// Package storetest provides a conformance suite for envsecret.Store implementations.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gavincabbage/envsecret"
+)
+
+// Names of the secrets the suite seeds stores with and asks them for. Options may map them to
+// identifiers suitable for a store, see IDs.
+const (
+	JSON    = "json"
+	Scalar  = "scalar"
+	Login   = "login"
+	Missing = "missing"
+)
+
+// Factory returns a store holding the given fixtures, keyed by identifier. Scalar fixtures,
+// those with a single "value" entry, should be stored as plain values rather than JSON so that
+// the store's handling of them is exercised; Encode does so.
+type Factory func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store
+
+// Option configures the suite for the capabilities of a store.
+type Option func(*config)
+
+type config struct {
+	id           func(name string) string
+	withContext  func(envsecret.Store, context.Context) envsecret.Store
+	skipMissing  bool
+	stringValues bool
+}
+
+// IDs maps the suite's secret names to identifiers for the store, e.g. to add a file extension.
+func IDs(fn func(name string) string) Option {
+	return func(c *config) { c.id = fn }
+}
+
+// WithContext enables the context cancellation tests for stores able to bind a context, given
+// a function returning the store bound to ctx.
+func WithContext(fn func(store envsecret.Store, ctx context.Context) envsecret.Store) Option {
+	return func(c *config) { c.withContext = fn }
+}
+
+// SkipMissing skips the tests of missing secrets for stores unable to report one, such as
+// store/local and store/kms, whose identifiers hold the secret itself, and store/exec.
+func SkipMissing() Option {
+	return func(c *config) { c.skipMissing = true }
+}
+
+// StringValues expects the JSON object to be returned as a flat map of strings, as given by
+// Strings, for stores holding each secret as named string values, such as store/kubernetes.
+func StringValues() Option {
+	return func(c *config) { c.stringValues = true }
+}
+
+// Strings returns the secret as a flat map of strings, for stores holding named string values:
+// strings are kept as they are and any other value is encoded as JSON.
+func Strings(secret map[string]interface{}) map[string]string {
+	m := make(map[string]string, len(secret))
+	for k, v := range secret {
+		if str, ok := v.(string); ok {
+			m[k] = str
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("encoding fixture: %v", err))
+		}
+		m[k] = string(b)
+	}
+
+	return m
+}
+
+// Encode returns the raw value a store should hold for the secret: a scalar secret's plain
+// value, or else the secret as a JSON object.
+func Encode(secret map[string]interface{}) []byte {
+	if v, found := secret["value"]; found && len(secret) == 1 {
+		return []byte(fmt.Sprint(v))
+	}
+
+	b, err := json.Marshal(secret)
+	if err != nil {
+		panic(fmt.Sprintf("encoding fixture: %v", err))
+	}
+	return b
+}
+
+// Fixtures returns the secrets the suite seeds stores with, keyed by name.
+func Fixtures() envsecret.Fixtures {
+	return envsecret.Fixtures{
+		JSON: {
+			"string": "value",
+			"number": float64(42.5),
+			"bool":   true,
+			"nested": map[string]interface{}{"key": "nested value", "list": []interface{}{"a", float64(1)}},
+		},
+		Scalar: {"value": "plain value"},
+		Login:  {"username": "user", "password": "pass"},
+	}
+}
+
+// Run the conformance suite against stores returned by the factory.
+func Run(t *testing.T, factory Factory, options ...Option) {
+	c := &config{
+		id: func(name string) string { return name },
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	fixtures := make(envsecret.Fixtures)
+	for name, secret := range Fixtures() {
+		fixtures[c.id(name)] = secret
+	}
+	newStore := func(t *testing.T) envsecret.Store {
+		t.Helper()
+		return factory(t, fixtures)
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		if c.skipMissing {
+			t.Skip("store cannot report missing secrets")
+		}
+
+		m, err := newStore(t).Get(c.id(Missing))
+		if !errors.Is(err, envsecret.ErrNotFound) {
+			t.Errorf("expected an error wrapping envsecret.ErrNotFound, got %v", err)
+		}
+		if m != nil {
+			t.Errorf("expected no secret alongside the error, got %v", m)
+		}
+	})
+
+	t.Run("JSON object", func(t *testing.T) {
+		m, err := newStore(t).Get(c.id(JSON))
+		if err != nil {
+			t.Fatalf("getting %s: %v", c.id(JSON), err)
+		}
+		var expected interface{} = Fixtures()[JSON]
+		if c.stringValues {
+			flat := make(map[string]interface{})
+			for k, v := range Strings(Fixtures()[JSON]) {
+				flat[k] = v
+			}
+			expected = flat
+		}
+		if !reflect.DeepEqual(normalize(m), expected) {
+			t.Errorf("expected %v, got %v", expected, m)
+		}
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		m, err := newStore(t).Get(c.id(Scalar))
+		if err != nil {
+			t.Fatalf("getting %s: %v", c.id(Scalar), err)
+		}
+		if len(m) != 1 {
+			t.Fatalf("expected a single value, got %v", m)
+		}
+		for _, v := range m {
+			if v != "plain value" {
+				t.Errorf("expected %q, got %v", "plain value", v)
+			}
+		}
+	})
+
+	t.Run("process", func(t *testing.T) {
+		spec := struct {
+			Scalar envsecret.String
+			String envsecret.String `secret_keys:"string"`
+			Number envsecret.String `secret_keys:"number"`
+			Login  envsecret.Login
+			Map    envsecret.Map
+		}{
+			Scalar: envsecret.NewString(c.id(Scalar)),
+			String: envsecret.NewString(c.id(JSON)),
+			Number: envsecret.NewString(c.id(JSON)),
+			Login:  envsecret.NewLogin(c.id(Login)),
+			Map:    envsecret.NewMap(c.id(JSON)),
+		}
+		if err := envsecret.Process(&spec, newStore(t)); err != nil {
+			t.Fatalf("processing: %v", err)
+		}
+
+		for _, field := range []struct {
+			name             string
+			actual, expected string
+		}{
+			{"String", spec.Scalar.Value, "plain value"},
+			{"String with secret_keys", spec.String.Value, "value"},
+			{"String of a number", spec.Number.Value, "42.5"},
+			{"Login username", spec.Login.Username, "user"},
+			{"Login password", spec.Login.Password, "pass"},
+		} {
+			if field.actual != field.expected {
+				t.Errorf("%s: expected %q, got %q", field.name, field.expected, field.actual)
+			}
+		}
+		if len(spec.Map.Values) != len(Fixtures()[JSON]) {
+			t.Errorf("expected %d map values, got %v", len(Fixtures()[JSON]), spec.Map.Values)
+		}
+
+		if c.skipMissing {
+			return
+		}
+		spec.Login = envsecret.NewLogin(c.id(Missing))
+		if err := envsecret.Process(&spec, newStore(t)); !errors.Is(err, envsecret.ErrNotFound) {
+			t.Errorf("expected processing a missing secret to fail with envsecret.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		store := newStore(t)
+
+		names := []string{JSON, Scalar, Login, Missing}
+		if c.skipMissing {
+			names = names[:3]
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 32)
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				name := names[i%len(names)]
+				m, err := store.Get(c.id(name))
+				switch {
+				case name == Missing && !errors.Is(err, envsecret.ErrNotFound):
+					errs <- fmt.Errorf("getting %s: expected envsecret.ErrNotFound, got %v", name, err)
+				case name != Missing && err != nil:
+					errs <- fmt.Errorf("getting %s: %w", name, err)
+				case name == Login && !reflect.DeepEqual(m, Fixtures()[Login]):
+					errs <- fmt.Errorf("getting %s: expected %v, got %v", name, Fixtures()[Login], m)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		if c.withContext == nil {
+			t.Skip("store does not bind a context")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		m, err := c.withContext(newStore(t), ctx).Get(c.id(JSON))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected an error wrapping context.Canceled, got %v", err)
+		}
+		if errors.Is(err, envsecret.ErrNotFound) {
+			t.Errorf("expected cancellation not to be reported as envsecret.ErrNotFound, got %v", err)
+		}
+		if m != nil {
+			t.Errorf("expected no secret alongside the error, got %v", m)
+		}
+	})
+}
+
+// normalize numbers, which stores may decode as json.Number or integers, to float64.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = normalize(e)
+		}
+		return s
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+
+	return v
+}
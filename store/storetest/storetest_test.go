@@ -0,0 +1,24 @@
+package storetest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/envsecrettest"
+	"github.com/gavincabbage/envsecret/store/storetest"
+)
+
+func TestRun(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		return envsecrettest.NewMemoryStore(fixtures)
+	}, storetest.IDs(func(name string) string {
+		return "prefix/" + name
+	}))
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "plain value", string(storetest.Encode(map[string]interface{}{"value": "plain value"})))
+	assert.JSONEq(t, `{"username":"user","port":5432}`, string(storetest.Encode(map[string]interface{}{"username": "user", "port": 5432})))
+}
@@ -1,32 +1,149 @@
 package vault
 
 import (
-	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
+
+	"github.com/gavincabbage/envsecret"
 )
 
-// SecretsManager provides access to AWS Secrets Manager.
+// Vault provides access to HashiCorp Vault.
 type Vault struct {
 	client *api.Client
 }
 
-// NewSecretsManager returns a SecretsManager instance configured to use the given AWS Secrets Manager client.
+// New returns a Vault instance configured to use the given Vault client.
 func New(client *api.Client) *Vault {
 	return &Vault{
 		client: client,
 	}
 }
 
-// Get retrieves the secret from AWS Secrets Manager for the given identifier, either an ARN or the
-// configured name of the desired secret.
+// Get retrieves the secret at the given path, e.g. "secret/data/app" in a KV version 2 engine or
+// "database/creds/app" for a dynamic secret.
 func (v *Vault) Get(id string) (map[string]interface{}, error) {
+	s, err := v.read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Data, nil
+}
+
+// GetMetadata implements envsecret.MetadataStore. The lease of a dynamic secret gives its lease
+// TTL and expiry, and the "metadata" entry returned by KV version 2 engines its version and
+// creation time. Since every write creates a new version, the creation time of the latest version
+// is also when the secret was last updated.
+func (v *Vault) GetMetadata(id string) (map[string]interface{}, envsecret.Metadata, error) {
+	now := time.Now()
+
+	s, err := v.read(id)
+	if err != nil {
+		return nil, envsecret.Metadata{}, err
+	}
+
+	metadata := envsecret.Metadata{
+		LeaseTTL: time.Duration(s.LeaseDuration) * time.Second,
+	}
+	if s.LeaseID != "" && s.LeaseDuration > 0 {
+		metadata.Expires = now.Add(metadata.LeaseTTL)
+	}
+
+	if kv, ok := s.Data["metadata"].(map[string]interface{}); ok {
+		if version, found := kv["version"]; found {
+			metadata.Version = fmt.Sprint(version)
+		}
+		if created, ok := kv["created_time"].(string); ok {
+			metadata.Created, _ = time.Parse(time.RFC3339Nano, created)
+			metadata.Updated = metadata.Created
+		}
+	}
+
+	return s.Data, metadata, nil
+}
+
+// Put implements envsecret.WritableStore, writing the secret to the given path as is. Like the
+// values Get returns, secrets in a KV version 2 engine must be wrapped in a "data" entry, and Get
+// returns the version's "metadata" entry alongside it.
+func (v *Vault) Put(id string, secret map[string]interface{}) error {
+	_, err := v.client.Logical().Write(id, secret)
+	return err
+}
+
+// Delete implements envsecret.WritableStore. For a KV version 2 engine this deletes the latest
+// version of the secret, after which Get and Delete report it missing until a new version is put.
+func (v *Vault) Delete(id string) error {
+	if _, err := v.read(id); err != nil {
+		return err
+	}
+
+	_, err := v.client.Logical().Delete(id)
+	return err
+}
+
+// read the secret at the given path, reporting a missing secret, or a deleted or destroyed latest
+// version in a KV version 2 engine, as envsecret.ErrNotFound.
+func (v *Vault) read(id string) (*api.Secret, error) {
 	s, err := v.client.Logical().Read(id)
 	if err != nil {
 		return nil, err
 	} else if s == nil {
-		return nil, errors.New("missing secret")
+		return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
 	}
 
-	return s.Data, nil
+	if _, kv := s.Data["metadata"].(map[string]interface{}); kv {
+		if data, found := s.Data["data"]; found && data == nil {
+			return nil, fmt.Errorf("%w: %s", envsecret.ErrNotFound, id)
+		}
+	}
+
+	return s, nil
+}
+
+// List implements envsecret.ListStore, returning the path of every secret beneath the given
+// path, descending into nested paths. Prefixes in a KV version 2 engine are given as the paths
+// Get reads, e.g. "secret/data/tenants/", and are listed through the engine's metadata endpoint.
+func (v *Vault) List(prefix string) ([]string, error) {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+
+	listPrefix := prefix
+	if parts := strings.SplitN(prefix, "/", 3); len(parts) == 3 && parts[1] == "data" {
+		listPrefix = parts[0] + "/metadata/" + parts[2]
+	}
+
+	var ids []string
+	if err := v.list(prefix, listPrefix, &ids); err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// list appends the secrets beneath prefix, as listed at listPrefix, to ids.
+func (v *Vault) list(prefix, listPrefix string, ids *[]string) error {
+	s, err := v.client.Logical().List(listPrefix)
+	if err != nil {
+		return err
+	} else if s == nil {
+		return nil
+	}
+
+	keys, _ := s.Data["keys"].([]interface{})
+	for _, k := range keys {
+		key, _ := k.(string)
+		if strings.HasSuffix(key, "/") {
+			if err := v.list(prefix+key, listPrefix+key, ids); err != nil {
+				return err
+			}
+			continue
+		}
+		*ids = append(*ids, prefix+key)
+	}
+
+	return nil
 }
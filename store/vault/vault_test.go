@@ -1,12 +1,21 @@
 package vault_test
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/gavincabbage/envsecret"
+	"github.com/gavincabbage/envsecret/store/storetest"
 	secretstore "github.com/gavincabbage/envsecret/store/vault"
 )
 
@@ -58,3 +67,180 @@ func TestVault_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestVault_PutDelete(t *testing.T) {
+	client, err := api.NewClient(&api.Config{
+		Address: endpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.SetToken(os.Getenv("VAULT_DEV_ROOT_TOKEN_ID"))
+
+	subject := secretstore.New(client)
+
+	const id = "secret/data/put_delete"
+	expected := map[string]interface{}{"username": "user", "password": "pass"}
+	assert.NoError(t, envsecret.Seed(subject, envsecret.Fixtures{
+		id: {"data": expected},
+	}))
+
+	response, err := subject.Get(id)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response["data"])
+
+	assert.NoError(t, subject.Delete(id))
+	_, err = subject.Get(id)
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.True(t, errors.Is(subject.Delete(id), envsecret.ErrNotFound))
+
+	assert.True(t, errors.Is(subject.Delete("secret/data/never_written"), envsecret.ErrNotFound))
+}
+
+func TestVault_List(t *testing.T) {
+	client, err := api.NewClient(&api.Config{
+		Address: endpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.SetToken(os.Getenv("VAULT_DEV_ROOT_TOKEN_ID"))
+
+	subject := secretstore.New(client)
+
+	assert.NoError(t, envsecret.Seed(subject, envsecret.Fixtures{
+		"secret/data/tenants/a":        {"data": map[string]interface{}{"key": "key-a"}},
+		"secret/data/tenants/b":        {"data": map[string]interface{}{"key": "key-b"}},
+		"secret/data/tenants/nested/c": {"data": map[string]interface{}{"key": "key-c"}},
+	}))
+
+	ids, err := subject.List("secret/data/tenants")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret/data/tenants/a", "secret/data/tenants/b", "secret/data/tenants/nested/c"}, ids)
+
+	ids, err = subject.List("secret/data/none/")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestVault_GetMetadata(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/secret/data/kv2":
+			_, _ = io.WriteString(w, `{"data": {"data": {"key": "value"}, "metadata": {"created_time": "2019-03-01T00:00:00.5Z", "version": 3}}}`)
+		case "/v1/database/creds/app":
+			_, _ = io.WriteString(w, `{"lease_id": "database/creds/app/abc", "lease_duration": 3600, "renewable": true, "data": {"username": "v-app", "password": "pass"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := secretstore.New(client)
+
+	_, metadata, err := subject.GetMetadata("secret/data/kv2")
+	assert.NoError(t, err)
+	assert.Equal(t, envsecret.Metadata{
+		Version: "3",
+		Created: time.Date(2019, 3, 1, 0, 0, 0, 5e8, time.UTC),
+		Updated: time.Date(2019, 3, 1, 0, 0, 0, 5e8, time.UTC),
+	}, metadata)
+
+	assert.Equal(t, []string{"/v1/secret/data/kv2"}, requested)
+
+	before := time.Now()
+	spec := struct {
+		DB envsecret.Login
+	}{
+		DB: envsecret.NewLogin("database/creds/app"),
+	}
+	assert.NoError(t, envsecret.Process(&spec, subject))
+	assert.Equal(t, "v-app", spec.DB.Username)
+	assert.Equal(t, time.Hour, spec.DB.Metadata().LeaseTTL)
+	assert.WithinDuration(t, before.Add(time.Hour), spec.DB.ExpiresAt(), time.Minute)
+
+	_, _, err = subject.GetMetadata("secret/data/missing")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+}
+
+func TestVault_DeleteKV2(t *testing.T) {
+	var data map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			data = body.Data
+		case http.MethodDelete:
+			data = nil
+		default:
+			// Like Vault, answer with the metadata of a deleted version alongside a 404.
+			metadata := map[string]interface{}{"version": 1, "deletion_time": ""}
+			if data == nil {
+				metadata["deletion_time"] = "2019-03-01T00:00:00Z"
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": data, "metadata": metadata},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := secretstore.New(client)
+
+	expected := map[string]interface{}{"key": "value"}
+	assert.NoError(t, subject.Put("secret/data/app", map[string]interface{}{"data": expected}))
+
+	response, err := subject.Get("secret/data/app")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response["data"])
+
+	assert.NoError(t, subject.Delete("secret/data/app"))
+	_, err = subject.Get("secret/data/app")
+	assert.True(t, errors.Is(err, envsecret.ErrNotFound))
+	assert.True(t, errors.Is(subject.Delete("secret/data/app"), envsecret.ErrNotFound))
+}
+
+func TestVault_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, fixtures envsecret.Fixtures) envsecret.Store {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, found := fixtures[strings.TrimPrefix(r.URL.Path, "/v1/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": secret})
+		}))
+		t.Cleanup(server.Close)
+
+		client, err := api.NewClient(&api.Config{Address: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secretstore.New(client)
+	}, storetest.IDs(func(name string) string {
+		return "secret/" + name
+	}))
+}
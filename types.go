@@ -2,11 +2,18 @@ package envsecret
 
 import (
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Secret is the interface considered by Process. Custom secret types
@@ -22,12 +29,13 @@ type Secret interface {
 
 // Base type for all Secret implementations exposed by this package.
 type Base struct {
-	id string
+	id       string
+	metadata Metadata
 }
 
 // NewBase returns a new Base secret with the given identifier.
 func NewBase(id string) Base {
-	return Base{id}
+	return Base{id: id}
 }
 
 // Decode implements envconfig.Decoder and populates id.
@@ -37,6 +45,18 @@ func (s *Base) Decode(value string) error { s.id = value; return nil }
 // e.g. an ARN if using AWS Secrets Manager or a Vault secret path.
 func (s *Base) ID() string { return s.id }
 
+// Metadata returns the metadata of the secret retrieved by Process, if the store is a MetadataStore.
+func (s *Base) Metadata() Metadata { return s.metadata }
+
+// Version returns the version of the secret retrieved by Process, or "" if the store did not report it.
+func (s *Base) Version() string { return s.metadata.Version }
+
+// ExpiresAt returns when the secret retrieved by Process expires, or the zero time if the store
+// did not report it.
+func (s *Base) ExpiresAt() time.Time { return s.metadata.Expires }
+
+func (s *Base) setMetadata(m Metadata) { s.metadata = m }
+
 // String is a general purpose secret and holds a single string value.
 type String struct {
 	Base
@@ -56,6 +76,194 @@ func (s *String) Decode(secrets map[string]interface{}) error {
 	return nil
 }
 
+// Int is a secret holding a single integer, given as a whole JSON number or a decimal string.
+type Int struct {
+	Base
+	Value int
+}
+
+// NewInt builds a new Int type secret with the given id.
+func NewInt(id string) Int {
+	return Int{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Value with the secret integer.
+func (s *Int) Decode(secrets map[string]interface{}) error {
+	v, found := lookup(secrets, "value")
+	if !found {
+		return errors.New("finding secret in map")
+	}
+
+	switch v := v.(type) {
+	case int:
+		s.Value = v
+	case int64:
+		if int64(int(v)) != v {
+			return fmt.Errorf("secret %s is not an integer: %w", s.id, strconv.ErrRange)
+		}
+		s.Value = int(v)
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt || v >= math.MaxInt {
+			return fmt.Errorf("secret %s is not an integer", s.id)
+		}
+		s.Value = int(v)
+	case json.Number, string:
+		i, err := strconv.ParseInt(strings.TrimSpace(str(v)), 10, 0)
+		if err != nil {
+			return fmt.Errorf("secret %s is not an integer: %w", s.id, cause(err))
+		}
+		s.Value = int(i)
+	default:
+		return fmt.Errorf("secret %s is not an integer: %T", s.id, v)
+	}
+
+	return nil
+}
+
+// Float64 is a secret holding a single finite number, given as a JSON number or a string.
+type Float64 struct {
+	Base
+	Value float64
+}
+
+// NewFloat64 builds a new Float64 type secret with the given id.
+func NewFloat64(id string) Float64 {
+	return Float64{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Value with the secret number.
+func (s *Float64) Decode(secrets map[string]interface{}) error {
+	v, found := lookup(secrets, "value")
+	if !found {
+		return errors.New("finding secret in map")
+	}
+
+	switch v := v.(type) {
+	case float64:
+		s.Value = v
+	case int:
+		s.Value = float64(v)
+	case int64:
+		s.Value = float64(v)
+	case json.Number, string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(str(v)), 64)
+		if err != nil {
+			return fmt.Errorf("secret %s is not a number: %w", s.id, cause(err))
+		}
+		s.Value = f
+	default:
+		return fmt.Errorf("secret %s is not a number: %T", s.id, v)
+	}
+
+	if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+		return fmt.Errorf("secret %s is not a finite number", s.id)
+	}
+
+	return nil
+}
+
+// Bool is a secret holding a single boolean, given as a JSON boolean or a string accepted by
+// strconv.ParseBool, e.g. "true" or "0".
+type Bool struct {
+	Base
+	Value bool
+}
+
+// NewBool builds a new Bool type secret with the given id.
+func NewBool(id string) Bool {
+	return Bool{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Value with the secret boolean.
+func (s *Bool) Decode(secrets map[string]interface{}) error {
+	v, found := lookup(secrets, "value")
+	if !found {
+		return errors.New("finding secret in map")
+	}
+
+	switch v := v.(type) {
+	case bool:
+		s.Value = v
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("secret %s is not a boolean: %w", s.id, cause(err))
+		}
+		s.Value = b
+	default:
+		return fmt.Errorf("secret %s is not a boolean: %T", s.id, v)
+	}
+
+	return nil
+}
+
+// Duration is a secret holding a single duration, given as a string accepted by
+// time.ParseDuration, e.g. "30s". Numbers are rejected, since their unit would be ambiguous.
+type Duration struct {
+	Base
+	Value time.Duration
+}
+
+// NewDuration builds a new Duration type secret with the given id.
+func NewDuration(id string) Duration {
+	return Duration{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Value with the secret duration.
+func (s *Duration) Decode(secrets map[string]interface{}) error {
+	v, found := lookup(secrets, "value")
+	if !found {
+		return errors.New("finding secret in map")
+	}
+
+	value, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("secret %s is not a duration with a unit, e.g. \"30s\": %T", s.id, v)
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("secret %s is not a duration, e.g. \"30s\"", s.id)
+	}
+	s.Value = d
+
+	return nil
+}
+
+// URL is a secret holding a single absolute URL, e.g. a connection string including credentials.
+type URL struct {
+	Base
+	Value *url.URL
+}
+
+// NewURL builds a new URL type secret with the given id.
+func NewURL(id string) URL {
+	return URL{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Value with the parsed secret URL.
+func (s *URL) Decode(secrets map[string]interface{}) error {
+	v, found := lookup(secrets, "value")
+	if !found {
+		return errors.New("finding secret in map")
+	}
+
+	value, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("secret %s is not a URL: %T", s.id, v)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("secret %s is not a URL: %w", s.id, cause(err))
+	} else if !u.IsAbs() {
+		return fmt.Errorf("secret %s is not an absolute URL", s.id)
+	}
+	s.Value = u
+
+	return nil
+}
+
 // Map contains a map of secret strings, possibly filtered by an allowList.
 type Map struct {
 	Base
@@ -73,6 +281,32 @@ func (m *Map) Decode(secrets map[string]interface{}) error {
 	return nil
 }
 
+// Prefix contains every secret beneath a prefix, keyed by identifier, each possibly filtered
+// by an allowList. Populating it requires a store implementing ListStore.
+type Prefix struct {
+	Base
+	Values map[string]map[string]interface{}
+}
+
+// NewPrefix builds a new Prefix type secret with the given prefix as its id.
+func NewPrefix(prefix string) Prefix {
+	return Prefix{Base: Base{id: prefix}}
+}
+
+// Decode implements Secret and populates Values with the map of identifier to secret map.
+func (p *Prefix) Decode(secrets map[string]interface{}) error {
+	p.Values = make(map[string]map[string]interface{}, len(secrets))
+	for id, v := range secrets {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("secret %s is not a map", id)
+		}
+		p.Values[id] = m
+	}
+
+	return nil
+}
+
 // Login contains a username and password.
 type Login struct {
 	Base
@@ -184,6 +418,42 @@ func (k *PrivateKey) Decode(secrets map[string]interface{}) error {
 	return nil
 }
 
+// Certificate contains a TLS certificate chain and its private key.
+type Certificate struct {
+	Base
+	Certificate tls.Certificate
+}
+
+// NewCertificate builds a new Certificate type secret with the given id.
+func NewCertificate(id string) Certificate {
+	return Certificate{Base: Base{id: id}}
+}
+
+// Decode implements Secret and populates Certificate from the base64 encoded PEM certificate
+// chain and private key it looks for in the map.
+func (c *Certificate) Decode(secrets map[string]interface{}) error {
+	var (
+		certificate, foundCertificate = secrets["certificate"]
+		privateKey, foundPrivateKey   = secrets["private_key"]
+	)
+	if !foundCertificate || !foundPrivateKey {
+		return errors.New("finding certificate or private key in map")
+	}
+
+	certPEM, err := base64.StdEncoding.DecodeString(str(certificate))
+	if err != nil {
+		return err
+	}
+
+	keyPEM, err := base64.StdEncoding.DecodeString(str(privateKey))
+	if err != nil {
+		return err
+	}
+
+	c.Certificate, err = tls.X509KeyPair(certPEM, keyPEM)
+	return err
+}
+
 // find a secret value in a map - if there is only one option, return it, else return
 // the value of the given key (which may be an empty string if no corresponding value exists)
 func find(secrets map[string]interface{}, key string) string {
@@ -198,6 +468,33 @@ func find(secrets map[string]interface{}, key string) string {
 	return ""
 }
 
+// cause returns the cause of a parsing error, without the secret value the error would include.
+func cause(err error) error {
+	var (
+		numError *strconv.NumError
+		urlError *url.Error
+	)
+	if errors.As(err, &numError) {
+		return numError.Err
+	} else if errors.As(err, &urlError) {
+		return urlError.Err
+	}
+
+	return err
+}
+
+// lookup a secret value in a map as find does, without converting it to a string.
+func lookup(secrets map[string]interface{}, key string) (interface{}, bool) {
+	if len(secrets) == 1 {
+		for _, v := range secrets {
+			return v, true
+		}
+	}
+
+	v, found := secrets[key]
+	return v, found
+}
+
 func str(x interface{}) string {
 	return fmt.Sprintf("%v", x)
 }
@@ -1,7 +1,17 @@
 package envsecret_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -49,3 +59,189 @@ func TestPublicKey_Decode(t *testing.T) {
 	assert.Error(t, subject.Decode(encodedJunk))
 	assert.Error(t, subject.Decode(incorrectlyEncodedJunk))
 }
+
+func TestCertificate_Decode(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		encodedCertificate = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+		encodedPrivateKey  = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+		valid              = map[string]interface{}{
+			"certificate": encodedCertificate,
+			"private_key": encodedPrivateKey,
+		}
+		missingKey = map[string]interface{}{
+			"certificate": encodedCertificate,
+		}
+		mismatched = map[string]interface{}{
+			"certificate": encodedCertificate,
+			"private_key": testPrivateKey,
+		}
+		incorrectlyEncodedJunk = map[string]interface{}{
+			"certificate": "c29tZWp1bms!!K",
+			"private_key": encodedPrivateKey,
+		}
+	)
+
+	subject := &envsecret.Certificate{}
+
+	assert.NoError(t, subject.Decode(valid))
+	assert.Equal(t, der, subject.Certificate.Certificate[0])
+	assert.Error(t, subject.Decode(missingKey))
+	assert.Error(t, subject.Decode(mismatched))
+	assert.Error(t, subject.Decode(incorrectlyEncodedJunk))
+}
+
+func TestPrefix_Decode(t *testing.T) {
+	subject := &envsecret.Prefix{}
+
+	assert.NoError(t, subject.Decode(map[string]interface{}{
+		"tenants/a": map[string]interface{}{"value": "key-a"},
+	}))
+	assert.Equal(t, map[string]map[string]interface{}{"tenants/a": {"value": "key-a"}}, subject.Values)
+	assert.Error(t, subject.Decode(map[string]interface{}{"tenants/a": "key-a"}))
+}
+
+func TestInt_Decode(t *testing.T) {
+	cases := []struct {
+		name     string
+		secrets  map[string]interface{}
+		expected int
+		err      string
+	}{
+		{name: "json number", secrets: map[string]interface{}{"value": 8080.0}, expected: 8080},
+		{name: "json.Number", secrets: map[string]interface{}{"value": json.Number("-3")}, expected: -3},
+		{name: "yaml int", secrets: map[string]interface{}{"value": 42}, expected: 42},
+		{name: "string", secrets: map[string]interface{}{"value": " 443\n"}, expected: 443},
+		{name: "single key", secrets: map[string]interface{}{"port": "5432"}, expected: 5432},
+		{name: "fraction", secrets: map[string]interface{}{"value": 1.5}, err: "secret port is not an integer"},
+		{name: "invalid string", secrets: map[string]interface{}{"value": "abc"}, err: "secret port is not an integer: invalid syntax"},
+		{name: "bool", secrets: map[string]interface{}{"value": true}, err: "secret port is not an integer: bool"},
+		{name: "missing", secrets: map[string]interface{}{"a": "1", "b": "2"}, err: "finding secret in map"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := envsecret.NewInt("port")
+			err := subject.Decode(test.secrets)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, subject.Value)
+		})
+	}
+}
+
+func TestFloat64_Decode(t *testing.T) {
+	cases := []struct {
+		name     string
+		secrets  map[string]interface{}
+		expected float64
+		err      string
+	}{
+		{name: "json number", secrets: map[string]interface{}{"value": 0.25}, expected: 0.25},
+		{name: "yaml int", secrets: map[string]interface{}{"value": 2}, expected: 2},
+		{name: "string", secrets: map[string]interface{}{"value": "1e3"}, expected: 1000},
+		{name: "invalid string", secrets: map[string]interface{}{"value": "abc"}, err: "secret ratio is not a number: invalid syntax"},
+		{name: "not finite", secrets: map[string]interface{}{"value": "NaN"}, err: "secret ratio is not a finite number"},
+		{name: "bool", secrets: map[string]interface{}{"value": false}, err: "secret ratio is not a number: bool"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := envsecret.NewFloat64("ratio")
+			err := subject.Decode(test.secrets)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, subject.Value)
+		})
+	}
+}
+
+func TestBool_Decode(t *testing.T) {
+	cases := []struct {
+		name     string
+		secrets  map[string]interface{}
+		expected bool
+		err      string
+	}{
+		{name: "json bool", secrets: map[string]interface{}{"value": true}, expected: true},
+		{name: "string", secrets: map[string]interface{}{"value": "false"}, expected: false},
+		{name: "digit", secrets: map[string]interface{}{"value": "1"}, expected: true},
+		{name: "invalid string", secrets: map[string]interface{}{"value": "yes"}, err: "secret debug is not a boolean: invalid syntax"},
+		{name: "number", secrets: map[string]interface{}{"value": 1.0}, err: "secret debug is not a boolean: float64"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := envsecret.NewBool("debug")
+			err := subject.Decode(test.secrets)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, subject.Value)
+		})
+	}
+}
+
+func TestDuration_Decode(t *testing.T) {
+	cases := []struct {
+		name     string
+		secrets  map[string]interface{}
+		expected time.Duration
+		err      string
+	}{
+		{name: "string", secrets: map[string]interface{}{"value": "1m30s"}, expected: 90 * time.Second},
+		{name: "invalid string", secrets: map[string]interface{}{"value": "soon"}, err: `secret timeout is not a duration, e.g. "30s"`},
+		{name: "number", secrets: map[string]interface{}{"value": 30.0}, err: `secret timeout is not a duration with a unit, e.g. "30s": float64`},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			subject := envsecret.NewDuration("timeout")
+			err := subject.Decode(test.secrets)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, subject.Value)
+		})
+	}
+}
+
+func TestURL_Decode(t *testing.T) {
+	subject := envsecret.NewURL("db")
+
+	assert.NoError(t, subject.Decode(map[string]interface{}{"value": "postgres://user:pass@db:5432/app?sslmode=disable"}))
+	assert.Equal(t, "postgres", subject.Value.Scheme)
+	password, _ := subject.Value.User.Password()
+	assert.Equal(t, "pass", password)
+
+	assert.EqualError(t, subject.Decode(map[string]interface{}{"value": "/relative/path"}), "secret db is not an absolute URL")
+	assert.EqualError(t, subject.Decode(map[string]interface{}{"value": 1.0}), "secret db is not a URL: float64")
+
+	err := subject.Decode(map[string]interface{}{"value": "postgres://user:secret@db:bad/app"})
+	assert.Error(t, err)
+	assert.False(t, strings.Contains(err.Error(), "secret@"), "error should not include the secret value: %v", err)
+}